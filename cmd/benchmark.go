@@ -1,13 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
 	"github.com/lnsp/touchstone/pkg/benchmark/suites"
 	"github.com/lnsp/touchstone/pkg/config"
+	"github.com/lnsp/touchstone/pkg/environment"
+	"github.com/lnsp/touchstone/pkg/store"
 	"github.com/lnsp/touchstone/pkg/visual"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -15,33 +24,65 @@ import (
 
 var (
 	pattern    string
-	outDir     string
+	storeDir   string
 	visualFile string
+	varsFile   string
+	setVars    []string
+	runName    string
+	overrides  struct {
+		cris, ocis      []string
+		filter, exclude []string
+		runs, warmup    int
+		output          string
+	}
 )
 
+// defaultOutput is the result file of configs without output.
+const defaultOutput = "results.json"
+
 var benchmarkCmd = &cobra.Command{
 	Use:   "benchmark",
 	Short: "Run the benchmark suite",
 	Run: func(cmd *cobra.Command, args []string) {
-		files, err := filepath.Glob(pattern)
+		if _, err := visual.FormatOf(visualFile); err != nil {
+			logrus.WithError(err).Fatal("failed check report file")
+		}
+		configs := loadConfigs(cmd)
+		run, err := store.New(storeDir).Create(time.Now())
+		if err != nil {
+			logrus.WithError(err).Fatal("failed create run")
+		}
+		logFile, err := os.Create(run.Path(store.LogFile))
 		if err != nil {
-			logrus.WithError(err).Fatal("failed expand glob")
+			logrus.WithError(err).Fatal("failed create log")
 		}
+		defer logFile.Close()
+		logrus.SetOutput(io.MultiWriter(os.Stderr, logFile))
+		logrus.WithField("run", run.ID).Info("starting run")
 		var (
 			index   = benchmark.NewIndex()
 			entries []benchmark.MatrixEntry
+			cris    []string
+			ocis    []string
 		)
-		for _, file := range files {
-			logrus.WithField("file", file).Info("loading benchmark file")
-			cfg, err := config.Parse(file)
-			if err != nil {
-				logrus.WithError(err).Fatal("failed parse config")
-			}
-			out, err := cfg.MapOutput(outDir)
-			if err != nil {
-				logrus.WithError(err).Fatal("failed map output")
-			}
-			defer out.Close()
+		for _, cfg := range configs {
+			cris = appendUnique(cris, cfg.CRIs...)
+			ocis = appendUnique(ocis, cfg.OCIs...)
+		}
+		logrus.Info("collecting environment")
+		manifest := environment.Collect(Version, cris, ocis)
+		if err := run.WriteManifest(manifest); err != nil {
+			logrus.WithError(err).Fatal("failed write manifest")
+		}
+		effective := effectiveConfigs(configs)
+		var buf bytes.Buffer
+		printEffective(&buf, effective)
+		if err := run.WriteConfig(buf.Bytes()); err != nil {
+			logrus.WithError(err).Fatal("failed write config")
+		}
+		// configs sharing an output are stored in one result file
+		outputs := make(map[string]*benchmark.Results)
+		for i, cfg := range configs {
 			matrix, err := cfg.Matrix()
 			if err != nil {
 				logrus.WithError(err).Fatal("failed build matrix")
@@ -50,35 +91,241 @@ var benchmarkCmd = &cobra.Command{
 			if err != nil {
 				logrus.WithError(err).Fatal("failed matrix run")
 			}
-			encoder := json.NewEncoder(out)
-			if err := encoder.Encode(results); err != nil {
-				logrus.WithError(err).Fatal("failed json encode")
+			name := cfg.Output
+			if name == "" {
+				name = defaultOutput
+			}
+			doc, ok := outputs[name]
+			if !ok {
+				// entries are never null, even if the config selects no combination
+				doc = &benchmark.Results{Version: benchmark.ResultsVersion, Environment: manifest, Entries: []benchmark.MatrixEntry{}}
+				outputs[name] = doc
+			} else {
+				doc.Config += "---\n"
+			}
+			doc.Config += effective[i].Config
+			doc.Entries = append(doc.Entries, results...)
+			if err := run.WriteResults(name, doc); err != nil {
+				logrus.WithError(err).Fatal("failed write results")
 			}
 			entries = append(entries, results...)
 			// update index
 			matrix.Index(index)
 		}
-		if err := visual.Write(outDir+visualFile, entries, index); err != nil {
+		if err := visual.Write(run.Path(visualFile), &visual.Report{
+			Environments: []*environment.Manifest{manifest},
+			Entries:      entries,
+			Index:        index,
+		}); err != nil {
 			logrus.WithError(err).Fatal("failed write")
 		}
+		logrus.WithFields(logrus.Fields{
+			"run": run.ID,
+			"dir": run.Dir,
+		}).Info("stored results")
 	},
 }
 
-var listFilter []string
+// loadConfigs loads the configs of all files matching the pattern, or the config of the single
+// benchmark given by --run, and applies the overriding flags. It exits on failure.
+func loadConfigs(cmd *cobra.Command) []*config.Config {
+	var configs []*config.Config
+	if runName != "" {
+		if _, ok := suites.Registry.Lookup(runName); !ok {
+			var names []string
+			for _, bm := range suites.Registry.Benchmarks() {
+				names = append(names, bm.Name())
+			}
+			logrus.WithFields(logrus.Fields{
+				"name":       runName,
+				"benchmarks": strings.Join(names, ", "),
+			}).Fatal("unknown benchmark")
+		}
+		configs = []*config.Config{runConfig(runName)}
+	} else {
+		configs = loadFiles()
+	}
+	o := configOverrides(cmd)
+	for _, cfg := range configs {
+		if err := o.Apply(cfg); err != nil {
+			logrus.WithError(err).Fatal("failed override config")
+		}
+	}
+	return configs
+}
+
+// runConfig creates the config of a quick check of a single benchmark.
+// Its filter matches the name exactly, as plain names select all benchmarks they are a prefix of.
+func runConfig(name string) *config.Config {
+	return &config.Config{
+		CRIs:   []string{"containerd"},
+		OCIs:   []string{"runc"},
+		Filter: []string{"re:^" + regexp.QuoteMeta(name) + "$"},
+		Runs:   1,
+		Source: "--run " + name,
+	}
+}
+
+// configOverrides collects the config fields set by flags.
+func configOverrides(cmd *cobra.Command) *config.Overrides {
+	flags, o := cmd.Flags(), &config.Overrides{}
+	if flags.Changed("cri") {
+		o.CRIs = overrides.cris
+	}
+	if flags.Changed("oci") {
+		o.OCIs = overrides.ocis
+	}
+	if flags.Changed("filter") {
+		o.Filter = overrides.filter
+	}
+	if flags.Changed("exclude") {
+		o.Exclude = overrides.exclude
+	}
+	if flags.Changed("runs") {
+		o.Runs = &overrides.runs
+	}
+	if flags.Changed("warmup") {
+		o.Warmup = &overrides.warmup
+	}
+	if flags.Changed("output") {
+		o.Output = &overrides.output
+	}
+	return o
+}
+
+// addOverrideFlags adds the flags overriding config fields to the command.
+func addOverrideFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&overrides.cris, "cri", nil, "Override the CRIs, e.g. 'containerd,crio'")
+	cmd.Flags().StringSliceVar(&overrides.ocis, "oci", nil, "Override the OCI handlers, e.g. 'runc,runsc'")
+	cmd.Flags().StringArrayVar(&overrides.filter, "filter", nil, "Override the filter expressions, dropping the excludes of the config")
+	cmd.Flags().StringArrayVar(&overrides.exclude, "exclude", nil, "Override the exclude expressions, e.g. 'tag:slow'")
+	cmd.Flags().IntVar(&overrides.runs, "runs", 0, "Override the number of runs")
+	cmd.Flags().IntVar(&overrides.warmup, "warmup", 0, "Override the number of warmup runs")
+	cmd.Flags().StringVar(&overrides.output, "output", "", "Override the result file name in the run directory")
+	cmd.Flags().StringVar(&runName, "run", "", "Run a single benchmark without config file, defaults to containerd, runc and 1 run")
+}
+
+// loadFiles loads the configs of all files matching the pattern and exits on failure.
+func loadFiles() []*config.Config {
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		logrus.WithError(err).Fatal("failed expand glob")
+	}
+	vars, err := templateVars()
+	if err != nil {
+		logrus.WithError(err).Fatal("failed load template variables")
+	}
+	var configs []*config.Config
+	for _, file := range files {
+		logrus.WithField("file", file).Info("loading benchmark file")
+		loaded, err := config.Load(file, vars)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed parse config")
+		}
+		configs = append(configs, loaded...)
+	}
+	return configs
+}
+
+// templateVars collects the config template variables from the variables file and --set flags.
+func templateVars() (config.Vars, error) {
+	vars := config.Vars{}
+	if varsFile != "" {
+		var err error
+		if vars, err = config.LoadVars(varsFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := vars.Set(setVars); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+// appendUnique appends the values not yet contained in the slice.
+func appendUnique(slice []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, s := range slice {
+			if s == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			slice = append(slice, v)
+		}
+	}
+	return slice
+}
+
+var listFilter, listExclude []string
+var listLong, listJSON bool
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List available benchmarks",
 	Run: func(cmd *cobra.Command, args []string) {
-		filtered := benchmark.Filter(suites.All(), listFilter)
-		for _, b := range filtered {
-			fmt.Println(b.Name())
+		filtered, err := benchmark.Filter(suites.All(), listFilter, listExclude)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed filter benchmarks")
+		}
+		infos := make([]benchmark.Info, len(filtered))
+		for i, b := range filtered {
+			infos[i], _ = suites.Registry.Info(b.Name())
+		}
+		switch {
+		case listJSON:
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(infos); err != nil {
+				logrus.WithError(err).Fatal("failed json encode")
+			}
+		case listLong:
+			printInfos(os.Stdout, infos)
+		default:
+			for _, info := range infos {
+				fmt.Println(info.Name)
+			}
 		}
 	},
 }
 
+// printInfos writes a human-readable description of each benchmark.
+func printInfos(w io.Writer, infos []benchmark.Info) {
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, info.Name)
+		if info.Description != "" {
+			fmt.Fprintf(w, "  %s\n", info.Description)
+		}
+		fmt.Fprintf(w, "  tags: %s, estimate: %v, launches: %d\n", strings.Join(info.Tags, ", "), info.Estimate, info.Launches)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, m := range info.Metrics {
+			unit := m.Unit
+			if unit == "" {
+				unit = "-"
+			}
+			better := "lower is better"
+			if m.HigherIsBetter {
+				better = "higher is better"
+			}
+			fmt.Fprintf(tw, "    %s\t%s\t%s\t%s\n", m.Label, unit, better, m.Description)
+		}
+		tw.Flush()
+	}
+}
+
 func init() {
 	benchmarkCmd.Flags().StringVarP(&pattern, "file", "f", "default.yaml", "Input benchmark configuration")
-	benchmarkCmd.Flags().StringVarP(&outDir, "dir", "d", "", "Output destination directory")
-	benchmarkCmd.Flags().StringVarP(&visualFile, "html-file", "x", "index.html", "HTML visualisation file name")
-	listCmd.Flags().StringSliceVarP(&listFilter, "filter", "f", nil, "Filter expression")
+	benchmarkCmd.Flags().StringVarP(&storeDir, "dir", "d", "results", "Result store directory, each run is stored in a directory named by its ID")
+	benchmarkCmd.Flags().StringVarP(&visualFile, "html-file", "x", "index.html", "Report file name in the run directory, its extension selects the format: html, csv, md")
+	benchmarkCmd.Flags().StringVar(&varsFile, "vars", "", "YAML file with config template variables")
+	benchmarkCmd.Flags().StringArrayVar(&setVars, "set", nil, "Set a config template variable, e.g. 'runs=5'")
+	addOverrideFlags(benchmarkCmd)
+	listCmd.Flags().StringArrayVarP(&listFilter, "filter", "f", nil, "Filter expression, e.g. 'performance.*' or 'tag:startup,!slow'")
+	listCmd.Flags().StringArrayVarP(&listExclude, "exclude", "e", nil, "Exclude expression")
+	listCmd.Flags().BoolVarP(&listLong, "long", "l", false, "Print descriptions, tags and metrics")
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Print metadata as JSON")
 }
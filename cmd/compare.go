@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/benchmark/suites"
+	"github.com/lnsp/touchstone/pkg/stats"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareTest       string
+	compareAlpha      float64
+	compareConfidence float64
+	compareJSON       bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare baseline candidate",
+	Short: "Compare two result sets and flag significant changes",
+	Long: `Compare the metrics of two result sets, each given as result file, run directory or run ID.
+For each benchmark, label and CRI/OCI combination found in both, the change of the mean is tested
+using the raw reports of the runs. Changes with a p-value below alpha are significant.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if compareTest != stats.UTest && compareTest != stats.TTest {
+			logrus.WithField("test", compareTest).Fatal("unknown test, expected utest or ttest")
+		}
+		if !(compareAlpha > 0 && compareAlpha < 1) {
+			logrus.WithField("alpha", compareAlpha).Fatal("alpha must be between 0 and 1")
+		}
+		if !(compareConfidence > 0 && compareConfidence < 1) {
+			logrus.WithField("confidence", compareConfidence).Fatal("confidence must be between 0 and 1, e.g. 0.95")
+		}
+		baseline, err := readResults(storeDir, args[:1])
+		if err != nil {
+			logrus.WithError(err).Fatal("failed read baseline")
+		}
+		candidate, err := readResults(storeDir, args[1:])
+		if err != nil {
+			logrus.WithError(err).Fatal("failed read candidate")
+		}
+		changes := benchmark.Compare(baseline, candidate, compareTest, compareConfidence)
+		if compareJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(changes); err != nil {
+				logrus.WithError(err).Fatal("failed json encode")
+			}
+			return
+		}
+		printChanges(os.Stdout, changes)
+	},
+}
+
+// printChanges writes a table of the changes in the style of benchstat.
+// Insignificant changes are shown as ~, significant ones tell if the metric got better or worse.
+func printChanges(w io.Writer, changes []benchmark.Change) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "BENCHMARK\tLABEL\tCRI/OCI\tBASELINE\tCANDIDATE\tDELTA\t%g%% CI\tP\n", compareConfidence*100)
+	significant := 0
+	for _, c := range changes {
+		delta, interval, p := c.Error, "-", "-"
+		if c.Error == "" {
+			delta = "~"
+			if c.P < compareAlpha {
+				significant++
+				delta = fmt.Sprintf("%+.2f%%%s", c.Delta*100, verdict(c))
+			}
+			interval = fmt.Sprintf("[%+.2f%%, %+.2f%%]", c.Low*100, c.High*100)
+			p = fmt.Sprintf("p=%.3f n=%d+%d", c.P, c.Baseline.N, c.Candidate.N)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s/%s\t%s\t%s\t%s\t%s\t%s\n",
+			c.Benchmark, c.Label, c.CRI, c.OCI, formatSummary(c.Baseline), formatSummary(c.Candidate), delta, interval, p)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d of %d changes significant at alpha=%g (%s)\n", significant, len(changes), compareAlpha, compareTest)
+}
+
+// formatSummary formats the mean and the relative standard deviation of a sample.
+func formatSummary(s stats.Summary) string {
+	if s.N == 0 {
+		return "-"
+	}
+	if s.Mean == 0 {
+		return fmt.Sprintf("%.4g", s.Mean)
+	}
+	return fmt.Sprintf("%.4g ±%.0f%%", s.Mean, math.Abs(s.StdDev/s.Mean)*100)
+}
+
+// verdict tells if the change is an improvement according to the metric metadata of the registry.
+func verdict(c benchmark.Change) string {
+	info, ok := suites.Registry.Info(c.Benchmark)
+	if !ok {
+		return ""
+	}
+	metric, ok := info.Metric(c.Label)
+	if !ok {
+		return ""
+	}
+	if (c.Delta > 0) == metric.HigherIsBetter {
+		return " (better)"
+	}
+	return " (worse)"
+}
+
+func init() {
+	compareCmd.Flags().StringVarP(&storeDir, "dir", "d", "results", "Result store directory of the run IDs")
+	compareCmd.Flags().StringVar(&compareTest, "test", stats.UTest, "Significance test: utest (Mann-Whitney U) or ttest (Welch)")
+	compareCmd.Flags().Float64Var(&compareAlpha, "alpha", 0.05, "Significance level, between 0 and 1")
+	compareCmd.Flags().Float64Var(&compareConfidence, "confidence", 0.95, "Confidence level of the interval of the change, between 0 and 1")
+	compareCmd.Flags().BoolVar(&compareJSON, "json", false, "Print the changes as JSON")
+}
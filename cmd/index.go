@@ -3,10 +3,8 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"path/filepath"
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
-	"github.com/lnsp/touchstone/pkg/config"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -15,21 +13,9 @@ var indexCmd = &cobra.Command{
 	Use:   "index",
 	Short: "Output the report index of the given benchmarks",
 	Run: func(cmd *cobra.Command, args []string) {
-		files, err := filepath.Glob(pattern)
-		if err != nil {
-			logrus.WithError(err).Fatal("failed expand glob")
-		}
+		configs := loadConfigs(cmd)
 		index := benchmark.NewIndex()
-		for _, file := range files {
-			cfg, err := config.Parse(file)
-			if err != nil {
-				logrus.WithError(err).Fatal("failed parse config")
-			}
-			out, err := cfg.MapOutput(outDir)
-			if err != nil {
-				logrus.WithError(err).Fatal("failed map output")
-			}
-			defer out.Close()
+		for _, cfg := range configs {
 			matrix, err := cfg.Matrix()
 			if err != nil {
 				logrus.WithError(err).Fatal("failed build matrix")
@@ -46,4 +32,7 @@ var indexCmd = &cobra.Command{
 
 func init() {
 	indexCmd.Flags().StringVarP(&pattern, "file", "f", "default.yaml", "Input benchmark configuration")
+	indexCmd.Flags().StringVar(&varsFile, "vars", "", "YAML file with config template variables")
+	indexCmd.Flags().StringArrayVar(&setVars, "set", nil, "Set a config template variable, e.g. 'runs=5'")
+	addOverrideFlags(indexCmd)
 }
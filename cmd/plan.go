@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/config"
+	"github.com/lnsp/touchstone/pkg/store"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	planResults   []string
+	planJSON      bool
+	planEffective bool
+)
+
+// effectiveConfig is a config with its includes resolved.
+type effectiveConfig struct {
+	Source string `json:"source"`
+	Config string `json:"config"`
+}
+
+// plan is the expanded benchmark matrix of all config files.
+type plan struct {
+	Configs  []effectiveConfig    `json:"configs"`
+	Items    []benchmark.PlanItem `json:"items"`
+	Launches int                  `json:"launches"`
+	// Estimate is the expected duration in seconds.
+	Estimate float64 `json:"estimate"`
+}
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Show the benchmarks a run would execute and estimate its duration",
+	Run: func(cmd *cobra.Command, args []string) {
+		configs := loadConfigs(cmd)
+		history, err := loadHistory(storeDir, planResults)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed load results")
+		}
+		var p plan
+		p.Configs = effectiveConfigs(configs)
+		for _, cfg := range configs {
+			matrix, err := cfg.Matrix()
+			if err != nil {
+				logrus.WithError(err).Fatal("failed build matrix")
+			}
+			items, err := matrix.Plan(history)
+			if err != nil {
+				logrus.WithError(err).Fatal("failed plan matrix")
+			}
+			for _, item := range items {
+				item.File = cfg.Source
+				p.Items = append(p.Items, item)
+				p.Launches += item.Launches
+				p.Estimate += item.Estimate
+			}
+		}
+		if planJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(p); err != nil {
+				logrus.WithError(err).Fatal("failed json encode")
+			}
+			return
+		}
+		if planEffective {
+			printEffective(os.Stdout, p.Configs)
+			return
+		}
+		printPlan(os.Stdout, p)
+	},
+}
+
+// effectiveConfigs resolves the configs into YAML and exits on failure.
+func effectiveConfigs(configs []*config.Config) []effectiveConfig {
+	effective := make([]effectiveConfig, len(configs))
+	for i, cfg := range configs {
+		data, err := cfg.Effective()
+		if err != nil {
+			logrus.WithError(err).Fatal("failed encode config")
+		}
+		effective[i] = effectiveConfig{Source: cfg.Source, Config: string(data)}
+	}
+	return effective
+}
+
+// printEffective writes the configs as YAML documents headed by their source.
+func printEffective(w io.Writer, configs []effectiveConfig) {
+	for i, cfg := range configs {
+		if i > 0 {
+			fmt.Fprintln(w, "---")
+		}
+		fmt.Fprintf(w, "# %s\n%s", cfg.Source, cfg.Config)
+	}
+}
+
+// loadHistory reads the run times from the results of the stored runs.
+func loadHistory(dir string, ids []string) (*benchmark.History, error) {
+	history := benchmark.NewHistory()
+	results := store.New(dir)
+	for _, id := range ids {
+		run, err := results.Open(id)
+		if err != nil {
+			return nil, err
+		}
+		docs, err := run.Results()
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			history.Add(doc.Entries)
+		}
+	}
+	return history, nil
+}
+
+// printPlan writes the plan as table followed by the totals.
+func printPlan(w io.Writer, p plan) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tCRI\tOCI\tBENCHMARK\tPARAMS\tRUNS\tLAUNCHES\tESTIMATE")
+	for _, item := range p.Items {
+		estimate := "unknown"
+		if item.Source != benchmark.EstimateUnknown {
+			estimate = seconds(item.Estimate).String()
+			if item.Source == benchmark.EstimateResults {
+				estimate += " (results)"
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\n",
+			item.File, item.CRI, item.OCI, item.Benchmark, formatParams(item.Params), item.Runs, item.Launches, estimate)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "\n%d benchmarks, %d container launches, estimated %v\n", len(p.Items), p.Launches, seconds(p.Estimate))
+}
+
+// formatParams formats the parameters as sorted key=value list.
+func formatParams(params map[string]string) string {
+	if len(params) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(params))
+	for key, value := range params {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// seconds converts seconds into a duration rounded to seconds.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second)).Round(time.Second)
+}
+
+func init() {
+	planCmd.Flags().StringVarP(&pattern, "file", "f", "default.yaml", "Input benchmark configuration")
+	planCmd.Flags().StringVar(&varsFile, "vars", "", "YAML file with config template variables")
+	planCmd.Flags().StringArrayVar(&setVars, "set", nil, "Set a config template variable, e.g. 'runs=5'")
+	addOverrideFlags(planCmd)
+	planCmd.Flags().StringVarP(&storeDir, "dir", "d", "results", "Result store directory")
+	planCmd.Flags().StringSliceVarP(&planResults, "results", "r", nil, "IDs of earlier runs to estimate durations from, or 'latest'")
+	planCmd.Flags().BoolVar(&planJSON, "json", false, "Print the plan as JSON")
+	planCmd.Flags().BoolVar(&planEffective, "effective", false, "Print the effective configs with their includes resolved")
+}
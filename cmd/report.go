@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/benchmark/suites"
+	"github.com/lnsp/touchstone/pkg/environment"
+	"github.com/lnsp/touchstone/pkg/store"
+	"github.com/lnsp/touchstone/pkg/visual"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportOut     string
+	reportFormats []string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report [result file, run directory or run ID]...",
+	Short: "Render reports from saved results",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, format := range reportFormats {
+			if _, ok := visual.Formats[format]; !ok {
+				logrus.WithField("format", format).Fatal("unsupported report format")
+			}
+		}
+		docs, err := readResults(storeDir, args)
+		if err != nil {
+			logrus.WithError(err).Fatal("failed read results")
+		}
+		report := newReport(docs)
+		for _, format := range reportFormats {
+			name := reportOut + "." + format
+			if err := visual.Write(name, report); err != nil {
+				logrus.WithError(err).Fatal("failed write report")
+			}
+			logrus.WithField("file", name).Info("wrote report")
+		}
+	},
+}
+
+// readResults reads the results referenced by file name, run directory or ID of a run in the store.
+func readResults(dir string, refs []string) ([]benchmark.Results, error) {
+	var docs []benchmark.Results
+	for _, ref := range refs {
+		var (
+			results []benchmark.Results
+			err     error
+		)
+		info, statErr := os.Stat(ref)
+		switch {
+		case statErr == nil && !info.IsDir():
+			results, err = readResultFile(ref)
+		case statErr == nil:
+			results, err = readRun(filepath.Dir(ref), filepath.Base(ref))
+		default:
+			results, err = readRun(dir, ref)
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, results...)
+	}
+	return docs, nil
+}
+
+func readResultFile(name string) ([]benchmark.Results, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	results, err := benchmark.DecodeResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return results, nil
+}
+
+func readRun(dir, id string) ([]benchmark.Results, error) {
+	run, err := store.New(dir).Open(id)
+	if err != nil {
+		return nil, err
+	}
+	return run.Results()
+}
+
+// newReport merges the results of each source and indexes them using the benchmark registry.
+// Results of several hosts or runs are kept apart and named by their source.
+func newReport(docs []benchmark.Results) *visual.Report {
+	report := &visual.Report{Index: benchmark.NewIndex()}
+	for _, doc := range docs {
+		if doc.Environment != nil && !containsManifest(report.Environments, doc.Environment) {
+			report.Environments = append(report.Environments, doc.Environment)
+		}
+	}
+	names := sourceNames(report.Environments)
+	var (
+		sources []string
+		grouped = make(map[string][]benchmark.MatrixEntry)
+	)
+	for _, doc := range docs {
+		var source string
+		for i, manifest := range report.Environments {
+			if doc.Environment != nil && reflect.DeepEqual(manifest, doc.Environment) {
+				source = names[i]
+			}
+		}
+		if _, ok := grouped[source]; !ok {
+			sources = append(sources, source)
+		}
+		grouped[source] = append(grouped[source], doc.Entries...)
+	}
+	for _, source := range sources {
+		for _, entry := range benchmark.MergeEntries(grouped[source]) {
+			report.Entries = append(report.Entries, entry)
+			if len(sources) > 1 {
+				report.Sources = append(report.Sources, source)
+			}
+		}
+	}
+	report.Index.AddResults(report.Entries, suites.Registry)
+	return report
+}
+
+// sourceNames names each manifest by its hostname, adding the time it was collected if hosts repeat.
+func sourceNames(manifests []*environment.Manifest) []string {
+	hosts := make(map[string]int)
+	for _, m := range manifests {
+		hosts[m.Host.Hostname]++
+	}
+	names := make([]string, len(manifests))
+	for i, m := range manifests {
+		names[i] = m.Host.Hostname
+		if hosts[m.Host.Hostname] > 1 || names[i] == "" {
+			names[i] = strings.TrimSpace(names[i] + " " + m.CreatedAt.UTC().Format("20060102-150405"))
+		}
+	}
+	return names
+}
+
+func containsManifest(manifests []*environment.Manifest, manifest *environment.Manifest) bool {
+	for _, m := range manifests {
+		if reflect.DeepEqual(m, manifest) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	reportCmd.Flags().StringVarP(&storeDir, "dir", "d", "results", "Result store directory of the run IDs")
+	reportCmd.Flags().StringVarP(&reportOut, "out", "o", "report", "Report file name without extension")
+	reportCmd.Flags().StringSliceVar(&reportFormats, "format", []string{"html"}, "Report formats: html, csv, md")
+}
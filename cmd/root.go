@@ -5,6 +5,9 @@ import (
 	"os"
 
 	"github.com/lnsp/touchstone/pkg/runtime"
+	_ "github.com/lnsp/touchstone/pkg/runtime/docker"
+	_ "github.com/lnsp/touchstone/pkg/runtime/native"
+	_ "github.com/lnsp/touchstone/pkg/runtime/ocidirect"
 	"github.com/lnsp/touchstone/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -13,7 +16,6 @@ import (
 var Version = "dev"
 
 var verbosity string
-var knownCRIs = []string{"containerd", "crio"}
 var rootCmd = &cobra.Command{
 	Use:   "touchstone",
 	Short: "Touchstone is a benchmarking suite for CRI-compatible container runtimes.",
@@ -24,12 +26,14 @@ var versionCmd = &cobra.Command{
 	Short: "Print the current version",
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Printf("touchstone %s\n", Version)
-		for _, cri := range knownCRIs {
+		for _, cri := range util.KnownCRIs {
 			client, err := runtime.NewClient(util.GetCRIEndpoint(cri))
 			if err != nil {
 				logrus.WithError(err).WithField("cri", cri).Error("failed connect")
+				continue
 			}
-			fmt.Println(client.Name(), client.Version())
+			fmt.Println(client.Name(), client.Version(), "(CRI "+client.APIVersion()+")")
+			client.Close()
 		}
 	},
 }
@@ -48,6 +52,9 @@ func init() {
 	rootCmd.AddCommand(benchmarkCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(planCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(compareCmd)
 }
 
 // Execute runs the command executor.
@@ -1,17 +1,19 @@
 package benchmark
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"time"
 
+	"github.com/lnsp/touchstone/pkg/environment"
 	"github.com/lnsp/touchstone/pkg/runtime"
-	"github.com/lnsp/touchstone/pkg/util"
 	"github.com/sirupsen/logrus"
 )
 
 type Benchmark interface {
 	Name() string
-	Run(client *runtime.Client, handler string) (Report, error)
+	Run(client runtime.Runtime, handler string) (Report, error)
 	Labels() []string
 }
 
@@ -42,30 +44,6 @@ func (report ValueReport) Scale(n int) Report {
 	return ValueReport(result)
 }
 
-// Filter operates in-place on a slice of benchmarks.
-func Filter(items []Benchmark, filters []string) []Benchmark {
-	logrus.WithField("itemCount", len(items)).Debug("filtering items")
-	i := 0
-	for j := range items {
-		found := false
-		name := items[j].Name()
-		for _, f := range filters {
-			if strings.HasPrefix(name, f) {
-				found = true
-				break
-			}
-		}
-		if found {
-			items[i] = items[j]
-			i++
-		} else {
-			logrus.WithField("name", items[j].Name()).Debug("filtered item")
-		}
-
-	}
-	return items[:i]
-}
-
 type Index map[string]IndexEntry
 
 func NewIndex() Index {
@@ -73,17 +51,35 @@ func NewIndex() Index {
 }
 
 type IndexEntry struct {
-	Labels   []string `json:"labels"`
-	Datasets []int    `json:"datasets"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels"`
+	Datasets    []int    `json:"datasets"`
 }
 
 type Matrix struct {
-	CRIs  []string
-	OCIs  []string
-	Items []Benchmark
-	Runs  int
+	CRIs     []string
+	OCIs     []string
+	Items    []Benchmark
+	Runs     int
+	Registry *Registry
+	// Selection is the selection of Items from the Registry, overrides are merged into it.
+	Selection Selection
+	// Warmup is the number of runs before the measured ones, their reports are discarded.
+	Warmup int
+	// Quiescence enables the host noise guards if set.
+	Quiescence *Quiescence
+	// Factory creates the runtime of a CRI. It defaults to runtime.Dial.
+	Factory Factory
+	// Exclude removes the matching combinations, Include adds combinations.
+	Exclude []Combination
+	Include []Combination
+	// Overrides change the benchmarks and runs of matching combinations.
+	Overrides []Override
 }
 
+// Factory creates the runtime backend named by a CRI entry of the matrix.
+type Factory func(cri string) (runtime.Runtime, error)
+
 type MatrixEntry struct {
 	CRI     string         `json:"cri"`
 	OCI     string         `json:"oci"`
@@ -91,18 +87,57 @@ type MatrixEntry struct {
 }
 
 type MatrixResult struct {
-	Name       string   `json:"name"`
-	Aggregated Report   `json:"aggregated"`
-	Reports    []Report `json:"reports"`
+	Name       string      `json:"name"`
+	Aggregated Report      `json:"aggregated"`
+	Reports    []Report    `json:"reports"`
+	Failures   []Failure   `json:"failures,omitempty"`
+	Hosts      []HostState `json:"hosts,omitempty"`
+	// RunTime is the mean duration of the measured runs in seconds,
+	// excluding run fixtures and the wait for a quiet host.
+	RunTime float64 `json:"runTime,omitempty"`
+}
+
+// Results is the content of a benchmark output file, see ResultsVersion.
+type Results struct {
+	Version     int                   `json:"version"`
+	Environment *environment.Manifest `json:"environment"`
+	// Config is the effective configuration of the run as YAML.
+	Config  string        `json:"config,omitempty"`
+	Entries []MatrixEntry `json:"results"`
+}
+
+// Failure records a benchmark run that did not produce a report.
+type Failure struct {
+	Run   int    `json:"run"`
+	Error string `json:"error"`
+	Log   string `json:"log,omitempty"`
+}
+
+// LogError is implemented by errors that carry the output of a failed run.
+// Runs failing with such an error are recorded as failures instead of aborting the matrix.
+type LogError interface {
+	error
+	Log() []byte
 }
 
 func (m *Matrix) Index(index Index) {
-	for _, item := range m.Items {
+	items := append([]Benchmark{}, m.Items...)
+	for _, c := range m.Combinations() {
+		// combinations with invalid settings fail when they are run
+		selected, _, _ := m.Settings(c)
+		items = append(items, selected...)
+	}
+	for _, item := range items {
 		logrus.WithField("report", item.Name()).Debug("indexing item")
-		index[item.Name()] = IndexEntry{
+		entry := IndexEntry{
 			Labels:   item.Labels(),
 			Datasets: make([]int, 0),
 		}
+		if m.Registry != nil {
+			info, _ := m.Registry.Info(item.Name())
+			entry.Description = info.Description
+		}
+		index[item.Name()] = entry
 	}
 }
 
@@ -111,39 +146,42 @@ func (m *Matrix) createEntry(cri string, handler string) (MatrixEntry, error) {
 		"cri":     cri,
 		"handler": handler,
 	}).Info("evaluating matrix entry")
-	client, err := runtime.NewClient(util.GetCRIEndpoint(cri))
+	factory := m.Factory
+	if factory == nil {
+		factory = runtime.Dial
+	}
+	client, err := factory(cri)
 	if err != nil {
 		return MatrixEntry{}, fmt.Errorf("[%s:%s] failed to initialize client: %v", cri, handler, err)
 	}
 	defer client.Close()
-	results := make([]MatrixResult, 0, len(m.Items))
-	for _, bm := range m.Items {
+	if m.Quiescence != nil {
+		WarnGovernors()
+	}
+	ctx := context.Background()
+	items, runs, err := m.Settings(Combination{CRI: cri, OCI: handler})
+	if err != nil {
+		return MatrixEntry{}, fmt.Errorf("[%s:%s] %v", cri, handler, err)
+	}
+	results := make([]MatrixResult, 0, len(items))
+	for _, bm := range items {
 		logrus.WithFields(logrus.Fields{
 			"name": bm.Name(),
 		}).Info("running benchmark")
-		aggregated := Report(nil)
-		reports := make([]Report, 0, m.Runs)
-		for i := 0; i < m.Runs; i++ {
-			logrus.WithFields(logrus.Fields{
-				"name":  bm.Name(),
-				"index": i,
-			}).Debug("benchmark attempt")
-			report, err := bm.Run(client, handler)
-			if err != nil {
-				return MatrixEntry{}, fmt.Errorf("[%s:%s] failed to run benchmark: %v", cri, handler, err)
-			}
-			reports = append(reports, report)
-			if aggregated != nil {
-				aggregated = aggregated.Aggregate(report)
-			} else {
-				aggregated = report
+		if err := Setup(ctx, bm, client, handler); err != nil {
+			return MatrixEntry{}, fmt.Errorf("[%s:%s] failed to setup benchmark: %v", cri, handler, err)
+		}
+		result, err := m.runBenchmark(ctx, bm, client, handler, runs)
+		if err != nil {
+			if tdErr := Teardown(ctx, bm, client, handler); tdErr != nil {
+				logrus.WithError(tdErr).WithField("name", bm.Name()).Warn("failed to teardown benchmark")
 			}
+			return MatrixEntry{}, fmt.Errorf("[%s:%s] %v", cri, handler, err)
 		}
-		results = append(results, MatrixResult{
-			Name:       bm.Name(),
-			Aggregated: aggregated.Scale(m.Runs),
-			Reports:    reports,
-		})
+		if err := Teardown(ctx, bm, client, handler); err != nil {
+			return MatrixEntry{}, fmt.Errorf("[%s:%s] failed to teardown benchmark: %v", cri, handler, err)
+		}
+		results = append(results, result)
 	}
 	return MatrixEntry{
 		CRI:     cri,
@@ -152,48 +190,126 @@ func (m *Matrix) createEntry(cri string, handler string) (MatrixEntry, error) {
 	}, nil
 }
 
-func (m *Matrix) Run() ([]MatrixEntry, error) {
-	entries := make([]MatrixEntry, 0, len(m.CRIs)*len(m.OCIs))
-	for _, cri := range m.CRIs {
-		for _, oci := range m.OCIs {
-			entry, err := m.createEntry(cri, oci)
-			if err != nil {
-				logrus.WithError(err).WithFields(logrus.Fields{
-					"cri": cri,
-					"oci": oci,
-				}).Error("failed to evaluate entry")
-				return nil, err
-			}
-			entries = append(entries, entry)
+// runBenchmark runs the benchmark repeatedly and aggregates the reports of all successful runs.
+func (m *Matrix) runBenchmark(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string, runs int) (MatrixResult, error) {
+	aggregated := Report(nil)
+	reports := make([]Report, 0, runs)
+	failures := make([]Failure, 0)
+	var (
+		hosts   []HostState
+		elapsed time.Duration
+		timed   int
+	)
+	if err := m.warmup(ctx, bm, client, handler); err != nil {
+		return MatrixResult{}, err
+	}
+	for i := 0; i < runs; i++ {
+		logrus.WithFields(logrus.Fields{
+			"name":  bm.Name(),
+			"index": i,
+		}).Debug("benchmark attempt")
+		// a failing SetupRun releases what it acquired, so the next run sets up its fixtures again
+		if err := SetupRun(ctx, bm, client, handler); err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"name":  bm.Name(),
+				"index": i,
+			}).Warn("benchmark run setup failed")
+			failures = append(failures, Failure{
+				Run:   i,
+				Error: fmt.Sprintf("failed to setup benchmark run: %v", err),
+			})
+			continue
+		}
+		if m.Quiescence != nil {
+			hosts = append(hosts, m.Quiescence.Wait(bm, i))
+		}
+		start := time.Now()
+		report, err := bm.Run(client, handler)
+		elapsed += time.Since(start)
+		timed++
+		if tdErr := TeardownRun(ctx, bm, client, handler); tdErr != nil {
+			return MatrixResult{}, fmt.Errorf("failed to teardown benchmark run: %v", tdErr)
+		}
+		var logErr LogError
+		if errors.As(err, &logErr) {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"name":  bm.Name(),
+				"index": i,
+			}).Warn("benchmark attempt failed")
+			failures = append(failures, Failure{
+				Run:   i,
+				Error: err.Error(),
+				Log:   string(logErr.Log()),
+			})
+			continue
+		} else if err != nil {
+			return MatrixResult{}, fmt.Errorf("failed to run benchmark: %v", err)
+		}
+		reports = append(reports, report)
+		if aggregated != nil {
+			aggregated = aggregated.Aggregate(report)
+		} else {
+			aggregated = report
 		}
 	}
-	return entries, nil
+	if aggregated != nil {
+		aggregated = aggregated.Scale(len(reports))
+	}
+	var runTime float64
+	if timed > 0 {
+		runTime = elapsed.Seconds() / float64(timed)
+	}
+	return MatrixResult{
+		Name:       bm.Name(),
+		Aggregated: aggregated,
+		Reports:    reports,
+		Failures:   failures,
+		Hosts:      hosts,
+		RunTime:    runTime,
+	}, nil
 }
 
-type Suite []Benchmark
-
-func (bs Suite) Name() string {
-	names := make([]string, len(bs))
-	for i := range bs {
-		names[i] = bs[i].Name()
+// warmup runs the benchmark the number of warmup runs and discards the reports.
+// Failed runs carrying a log are only logged.
+func (m *Matrix) warmup(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string) error {
+	for i := 0; i < m.Warmup; i++ {
+		logrus.WithFields(logrus.Fields{
+			"name":  bm.Name(),
+			"index": i,
+		}).Debug("benchmark warmup")
+		if err := SetupRun(ctx, bm, client, handler); err != nil {
+			logrus.WithError(err).WithField("name", bm.Name()).Warn("benchmark warmup setup failed")
+			continue
+		}
+		_, err := bm.Run(client, handler)
+		if tdErr := TeardownRun(ctx, bm, client, handler); tdErr != nil {
+			return fmt.Errorf("failed to teardown warmup run: %v", tdErr)
+		}
+		var logErr LogError
+		if errors.As(err, &logErr) {
+			logrus.WithError(err).WithField("name", bm.Name()).Warn("benchmark warmup failed")
+		} else if err != nil {
+			return fmt.Errorf("failed to warm up benchmark: %v", err)
+		}
 	}
-	return fmt.Sprintf("Suite [%s]", strings.Join(names, ", "))
+	return nil
 }
 
-func (bs Suite) Run(client *runtime.Client, handler string) (interface{}, error) {
-	reports := make([]struct {
-		Name   string      `json:"name"`
-		Report interface{} `json:"report"`
-	}, len(bs))
-	for i := range bs {
-		report, err := bs[i].Run(client, handler)
+func (m *Matrix) Run() ([]MatrixEntry, error) {
+	combinations := m.Combinations()
+	entries := make([]MatrixEntry, 0, len(combinations))
+	for _, c := range combinations {
+		entry, err := m.createEntry(c.CRI, c.OCI)
 		if err != nil {
-			return nil, fmt.Errorf("failed to run suite %s: %v", bs[i].Name(), err)
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"cri": c.CRI,
+				"oci": c.OCI,
+			}).Error("failed to evaluate entry")
+			return nil, err
 		}
-		reports[i].Name = bs[i].Name()
-		reports[i].Report = report
+		entries = append(entries, entry)
 	}
-	return reports, nil
+	return entries, nil
 }
 
 // ID computes a unique string for this benchmark.
@@ -0,0 +1,165 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+)
+
+// fakeRuntime only supports closing, the benchmarks under test do not touch the runtime.
+type fakeRuntime struct {
+	runtime.Runtime
+	closed bool
+}
+
+func (rt *fakeRuntime) Close() { rt.closed = true }
+
+type fakeLogError struct{ log string }
+
+func (err *fakeLogError) Error() string { return "workload failed" }
+func (err *fakeLogError) Log() []byte   { return []byte(err.log) }
+
+// flakyBenchmark reports its run index and fails every second run.
+type flakyBenchmark struct {
+	runs int
+}
+
+func (bm *flakyBenchmark) Name() string     { return "flaky" }
+func (bm *flakyBenchmark) Labels() []string { return []string{"Run"} }
+func (bm *flakyBenchmark) Run(client runtime.Runtime, handler string) (Report, error) {
+	run := bm.runs
+	bm.runs++
+	if run%2 == 1 {
+		return nil, &fakeLogError{log: "out of luck"}
+	}
+	return ValueReport{"Run": float64(run)}, nil
+}
+
+func TestMatrixRun(t *testing.T) {
+	var runtimes []*fakeRuntime
+	matrix := &Matrix{
+		CRIs:  []string{"fake"},
+		OCIs:  []string{"runc", "runsc"},
+		Items: []Benchmark{&flakyBenchmark{}},
+		Runs:  4,
+		Factory: func(cri string) (runtime.Runtime, error) {
+			rt := &fakeRuntime{}
+			runtimes = append(runtimes, rt)
+			return rt, nil
+		},
+	}
+	entries, err := matrix.Run()
+	if err != nil {
+		t.Fatalf("failed to run matrix: %v", err)
+	}
+	if len(entries) != 2 || entries[0].OCI != "runc" || entries[1].OCI != "runsc" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	result := entries[0].Results[0]
+	if expected := (ValueReport{"Run": 1}); !reflect.DeepEqual(result.Aggregated, expected) {
+		t.Errorf("expected aggregate %v, got %v", expected, result.Aggregated)
+	}
+	if len(result.Reports) != 2 {
+		t.Errorf("expected 2 reports, got %d", len(result.Reports))
+	}
+	expectedFailures := []Failure{
+		{Run: 1, Error: "workload failed", Log: "out of luck"},
+		{Run: 3, Error: "workload failed", Log: "out of luck"},
+	}
+	if !reflect.DeepEqual(result.Failures, expectedFailures) {
+		t.Errorf("expected failures %v, got %v", expectedFailures, result.Failures)
+	}
+	for _, rt := range runtimes {
+		if !rt.closed {
+			t.Errorf("runtime was not closed")
+		}
+	}
+}
+
+// fixtureBenchmark fails the setup of every second run fixture.
+type fixtureBenchmark struct {
+	flakyBenchmark
+	setups int
+}
+
+func (bm *fixtureBenchmark) SetupRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	setup := bm.setups
+	bm.setups++
+	if setup%2 == 1 {
+		return errors.New("sandbox failed")
+	}
+	return nil
+}
+
+func (bm *fixtureBenchmark) TeardownRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	return nil
+}
+
+func TestMatrixRunSetupFailure(t *testing.T) {
+	matrix := &Matrix{
+		CRIs:  []string{"fake"},
+		OCIs:  []string{"runc"},
+		Items: []Benchmark{&fixtureBenchmark{}},
+		Runs:  3,
+		Factory: func(cri string) (runtime.Runtime, error) {
+			return &fakeRuntime{}, nil
+		},
+	}
+	entries, err := matrix.Run()
+	if err != nil {
+		t.Fatalf("failed to run matrix: %v", err)
+	}
+	result := entries[0].Results[0]
+	if expected := []Report{ValueReport{"Run": 0}}; !reflect.DeepEqual(result.Reports, expected) {
+		t.Errorf("expected reports %v, got %v", expected, result.Reports)
+	}
+	expectedFailures := []Failure{
+		{Run: 1, Error: "failed to setup benchmark run: sandbox failed"},
+		{Run: 2, Error: "workload failed", Log: "out of luck"},
+	}
+	if !reflect.DeepEqual(result.Failures, expectedFailures) {
+		t.Errorf("expected failures %v, got %v", expectedFailures, result.Failures)
+	}
+}
+
+func TestMatrixRunFactoryError(t *testing.T) {
+	matrix := &Matrix{
+		CRIs:  []string{"fake"},
+		OCIs:  []string{"runc"},
+		Items: []Benchmark{&flakyBenchmark{}},
+		Runs:  1,
+		Factory: func(cri string) (runtime.Runtime, error) {
+			return nil, errors.New("unreachable")
+		},
+	}
+	if _, err := matrix.Run(); err == nil {
+		t.Errorf("expected error for failing factory")
+	}
+}
+
+func TestMatrixRunWarmup(t *testing.T) {
+	matrix := &Matrix{
+		CRIs:   []string{"fake"},
+		OCIs:   []string{"runc"},
+		Items:  []Benchmark{&flakyBenchmark{}},
+		Runs:   2,
+		Warmup: 2,
+		Factory: func(cri string) (runtime.Runtime, error) {
+			return &fakeRuntime{}, nil
+		},
+	}
+	entries, err := matrix.Run()
+	if err != nil {
+		t.Fatalf("failed to run matrix: %v", err)
+	}
+	result := entries[0].Results[0]
+	if expected := []Report{ValueReport{"Run": 2}}; !reflect.DeepEqual(result.Reports, expected) {
+		t.Errorf("expected reports %v after warmup, got %v", expected, result.Reports)
+	}
+	if len(result.Failures) != 1 || result.Failures[0].Run != 1 {
+		t.Errorf("expected failure of run 1, got %v", result.Failures)
+	}
+}
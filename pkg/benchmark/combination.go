@@ -0,0 +1,181 @@
+package benchmark
+
+import (
+	"fmt"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+)
+
+// Combination is a pair of CRI and runtime handler evaluated by the matrix.
+// In rules, empty fields match any CRI or handler.
+type Combination struct {
+	CRI string `json:"cri" yaml:"cri,omitempty"`
+	OCI string `json:"oci" yaml:"oci,omitempty"`
+}
+
+// Matches checks if the rule matches the combination.
+func (rule Combination) Matches(c Combination) bool {
+	return (rule.CRI == "" || rule.CRI == c.CRI) && (rule.OCI == "" || rule.OCI == c.OCI)
+}
+
+func (c Combination) String() string {
+	return c.CRI + "/" + c.OCI
+}
+
+// Selection picks benchmarks by filter and exclude expressions and derives them with params.
+type Selection struct {
+	Filter  []string          `yaml:"filter,omitempty"`
+	Exclude []string          `yaml:"exclude,omitempty"`
+	Params  map[string]string `yaml:"params,omitempty"`
+}
+
+// Merge returns the selection with the fields set by o replaced. Params are merged key by key.
+func (s Selection) Merge(o Selection) Selection {
+	if o.Filter != nil {
+		s.Filter = o.Filter
+	}
+	if o.Exclude != nil {
+		s.Exclude = o.Exclude
+	}
+	if o.Params != nil {
+		params := make(map[string]string, len(s.Params)+len(o.Params))
+		for key, value := range s.Params {
+			params[key] = value
+		}
+		for key, value := range o.Params {
+			params[key] = value
+		}
+		s.Params = params
+	}
+	return s
+}
+
+// IsZero checks if the selection sets no field.
+func (s Selection) IsZero() bool {
+	return s.Filter == nil && s.Exclude == nil && s.Params == nil
+}
+
+// Select returns the selected benchmarks derived with the params they declare.
+func (s Selection) Select(items []Benchmark) ([]Benchmark, error) {
+	selected, err := Filter(items, s.Filter, s.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	// Params may derive the same benchmark from several selected ones
+	derived := make([]Benchmark, 0, len(selected))
+	seen := make(map[string]bool)
+	for _, item := range selected {
+		item, err := WithParams(item, s.Params)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[item.Name()] {
+			seen[item.Name()] = true
+			derived = append(derived, item)
+		}
+	}
+	return derived, nil
+}
+
+// Override changes the settings of the matrix for the combinations it matches.
+// Empty fields of the combination match any CRI or handler, unset fields keep the settings in effect.
+type Override struct {
+	Combination `yaml:",inline"`
+	// Runs replaces the number of runs if positive.
+	Runs int `yaml:"runs,omitempty"`
+	// Selection is merged into the selection in effect, see Selection.Merge.
+	Selection `yaml:",inline"`
+}
+
+// Combinations returns the combinations of the matrix. These are the CRIs with their supported
+// handlers, without the excluded combinations and followed by the included ones.
+func (m *Matrix) Combinations() []Combination {
+	var combinations []Combination
+	seen := make(map[Combination]bool)
+	for _, cri := range m.CRIs {
+		for _, oci := range runtime.Handlers(cri, m.OCIs) {
+			c := Combination{CRI: cri, OCI: oci}
+			if m.excluded(c) || seen[c] {
+				continue
+			}
+			seen[c] = true
+			combinations = append(combinations, c)
+		}
+	}
+	for _, c := range m.Include {
+		if !seen[c] {
+			seen[c] = true
+			combinations = append(combinations, c)
+		}
+	}
+	return combinations
+}
+
+func (m *Matrix) excluded(c Combination) bool {
+	for _, rule := range m.Exclude {
+		if rule.Matches(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings returns the benchmarks and number of runs of the combination.
+// Matching overrides are applied in order, each replacing only the fields it sets, so later ones take precedence.
+// If an override changes the selection, the benchmarks are selected from the registry again.
+func (m *Matrix) Settings(c Combination) ([]Benchmark, int, error) {
+	selection, runs, changed := m.Selection, m.Runs, false
+	for _, o := range m.Overrides {
+		if !o.Matches(c) {
+			continue
+		}
+		if o.Runs > 0 {
+			runs = o.Runs
+		}
+		if !o.Selection.IsZero() {
+			selection, changed = selection.Merge(o.Selection), true
+		}
+	}
+	if !changed {
+		return m.Items, runs, nil
+	}
+	candidates := m.Items
+	if m.Registry != nil {
+		candidates = m.Registry.Benchmarks()
+	}
+	items, err := selection.Select(candidates)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: %v", c, err)
+	}
+	return items, runs, nil
+}
+
+// Configurable is implemented by parameterized benchmarks that can be derived with other parameters.
+type Configurable interface {
+	Parameterized
+	WithParams(params map[string]string) (Benchmark, error)
+}
+
+// WithParams derives a benchmark using the given parameters it declares.
+// Benchmarks without any of the parameters are returned unchanged.
+func WithParams(bm Benchmark, params map[string]string) (Benchmark, error) {
+	configurable, ok := bm.(Configurable)
+	if !ok {
+		return bm, nil
+	}
+	own := configurable.Params()
+	applied := make(map[string]string)
+	for key, value := range params {
+		if _, ok := own[key]; ok {
+			applied[key] = value
+		}
+	}
+	if len(applied) == 0 {
+		return bm, nil
+	}
+	derived, err := configurable.WithParams(applied)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params for %s: %v", bm.Name(), err)
+	}
+	return derived, nil
+}
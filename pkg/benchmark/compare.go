@@ -0,0 +1,82 @@
+package benchmark
+
+import (
+	"sort"
+
+	"github.com/lnsp/touchstone/pkg/stats"
+)
+
+// MetricKey identifies the values of a label reported by a benchmark for a CRI and handler.
+type MetricKey struct {
+	Benchmark string `json:"benchmark"`
+	Label     string `json:"label"`
+	CRI       string `json:"cri"`
+	OCI       string `json:"oci"`
+}
+
+// Samples collects the values of each metric from the reports of the runs.
+// The keys are returned in order of their first appearance.
+func Samples(docs []Results) ([]MetricKey, map[MetricKey][]float64) {
+	var (
+		keys    []MetricKey
+		samples = make(map[MetricKey][]float64)
+	)
+	for _, doc := range docs {
+		for _, entry := range doc.Entries {
+			for _, result := range entry.Results {
+				for _, report := range result.Reports {
+					values, _ := report.(ValueReport)
+					for _, label := range sortedLabels(values) {
+						key := MetricKey{Benchmark: result.Name, Label: label, CRI: entry.CRI, OCI: entry.OCI}
+						if _, ok := samples[key]; !ok {
+							keys = append(keys, key)
+						}
+						samples[key] = append(samples[key], values[label])
+					}
+				}
+			}
+		}
+	}
+	return keys, samples
+}
+
+func sortedLabels(values ValueReport) []string {
+	labels := make([]string, 0, len(values))
+	for label := range values {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// Change is the comparison of a metric measured by baseline and candidate results.
+type Change struct {
+	MetricKey
+	stats.Comparison
+	// Error tells why the metric could not be compared.
+	Error string `json:"error,omitempty"`
+}
+
+// Compare compares the metrics found in both the baseline and the candidate results using the test,
+// see stats.Compare.
+func Compare(baseline, candidate []Results, test string, confidence float64) []Change {
+	keys, before := Samples(baseline)
+	_, after := Samples(candidate)
+	var changes []Change
+	for _, key := range keys {
+		values, ok := after[key]
+		if !ok {
+			continue
+		}
+		change := Change{MetricKey: key}
+		comparison, err := stats.Compare(before[key], values, test, confidence)
+		if err != nil {
+			change.Error = err.Error()
+			change.Baseline, change.Candidate = stats.Summarize(before[key]), stats.Summarize(values)
+		} else {
+			change.Comparison = comparison
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
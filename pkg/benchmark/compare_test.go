@@ -0,0 +1,39 @@
+package benchmark
+
+import (
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/stats"
+)
+
+func sampleResults(cri, oci string, values ...float64) []Results {
+	reports := make([]Report, len(values))
+	for i, v := range values {
+		reports[i] = ValueReport{"Time": v}
+	}
+	return []Results{{Entries: []MatrixEntry{{
+		CRI:     cri,
+		OCI:     oci,
+		Results: []MatrixResult{{Name: "flaky", Reports: reports}},
+	}}}}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := append(sampleResults("containerd", "runsc", 10, 11, 9, 10, 10), sampleResults("containerd", "runc", 1)...)
+	candidate := append(sampleResults("containerd", "runsc", 12, 13, 11, 12, 12), sampleResults("containerd", "runc", 1, 2)...)
+	candidate = append(candidate, sampleResults("crio", "runsc", 1, 2)...)
+	changes := Compare(baseline, candidate, stats.UTest, 0.95)
+	if len(changes) != 2 {
+		t.Fatalf("expected changes of 2 metrics found in both result sets, got %+v", changes)
+	}
+	runsc := changes[0]
+	if runsc.OCI != "runsc" || runsc.Label != "Time" || runsc.Error != "" || runsc.Baseline.N != 5 {
+		t.Errorf("unexpected change %+v", runsc)
+	}
+	if runsc.Delta < 0.19 || runsc.Delta > 0.21 || runsc.P > 0.05 {
+		t.Errorf("expected significant change of 20%%, got %+v", runsc)
+	}
+	if runc := changes[1]; runc.Error != stats.ErrSampleSize.Error() {
+		t.Errorf("expected sample size error for a single baseline run, got %+v", runc)
+	}
+}
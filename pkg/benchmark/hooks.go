@@ -0,0 +1,66 @@
+package benchmark
+
+import (
+	"context"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+)
+
+// Imaged is implemented by benchmarks that depend on container images.
+// The images are pulled once per matrix entry, outside of the timed runs.
+type Imaged interface {
+	Images() []string
+}
+
+// EntryHooks is implemented by benchmarks that prepare fixtures once per matrix entry.
+// Teardown is only called after a successful Setup, so a failing Setup releases what it acquired itself.
+type EntryHooks interface {
+	Setup(ctx context.Context, client runtime.Runtime, handler string) error
+	Teardown(ctx context.Context, client runtime.Runtime, handler string) error
+}
+
+// RunHooks is implemented by benchmarks that prepare fixtures before each run.
+// As with EntryHooks, a failing SetupRun releases what it acquired itself.
+type RunHooks interface {
+	SetupRun(ctx context.Context, client runtime.Runtime, handler string) error
+	TeardownRun(ctx context.Context, client runtime.Runtime, handler string) error
+}
+
+// Setup pulls the images of the benchmark and calls its entry setup hook.
+func Setup(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string) error {
+	if imaged, ok := bm.(Imaged); ok {
+		for _, image := range imaged.Images() {
+			if err := client.PullImage(image, nil); err != nil {
+				return err
+			}
+		}
+	}
+	if hooks, ok := bm.(EntryHooks); ok {
+		return hooks.Setup(ctx, client, handler)
+	}
+	return nil
+}
+
+// Teardown calls the entry teardown hook of the benchmark.
+func Teardown(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string) error {
+	if hooks, ok := bm.(EntryHooks); ok {
+		return hooks.Teardown(ctx, client, handler)
+	}
+	return nil
+}
+
+// SetupRun calls the run setup hook of the benchmark.
+func SetupRun(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string) error {
+	if hooks, ok := bm.(RunHooks); ok {
+		return hooks.SetupRun(ctx, client, handler)
+	}
+	return nil
+}
+
+// TeardownRun calls the run teardown hook of the benchmark.
+func TeardownRun(ctx context.Context, bm Benchmark, client runtime.Runtime, handler string) error {
+	if hooks, ok := bm.(RunHooks); ok {
+		return hooks.TeardownRun(ctx, client, handler)
+	}
+	return nil
+}
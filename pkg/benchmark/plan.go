@@ -0,0 +1,146 @@
+package benchmark
+
+import "time"
+
+// Parameterized is implemented by benchmarks whose instances differ in their parameters, like the number of sandboxes.
+type Parameterized interface {
+	Params() map[string]string
+}
+
+// Params returns the parameters of the benchmark.
+func Params(bm Benchmark) map[string]string {
+	if p, ok := bm.(Parameterized); ok {
+		return p.Params()
+	}
+	return nil
+}
+
+// Sources of plan estimates.
+const (
+	EstimateResults  = "results"
+	EstimateRegistry = "registry"
+	EstimateUnknown  = "unknown"
+)
+
+// PlanItem is a benchmark the matrix runs for a CRI and runtime handler.
+type PlanItem struct {
+	File      string            `json:"file,omitempty"`
+	CRI       string            `json:"cri"`
+	OCI       string            `json:"oci"`
+	Benchmark string            `json:"benchmark"`
+	Params    map[string]string `json:"params,omitempty"`
+	Runs      int               `json:"runs"`
+	Warmup    int               `json:"warmup,omitempty"`
+	// Launches is the number of containers started by all runs including the warmup.
+	Launches int `json:"launches"`
+	// Estimate is the expected duration of all runs including the warmup in seconds.
+	Estimate float64 `json:"estimate"`
+	// Source tells where the estimate comes from.
+	Source string `json:"source"`
+}
+
+// Plan expands the matrix into the benchmarks it would run without contacting any runtime.
+// Estimates are taken from the history if available and from the registry otherwise.
+func (m *Matrix) Plan(history *History) ([]PlanItem, error) {
+	var items []PlanItem
+	for _, c := range m.Combinations() {
+		benchmarks, runs, err := m.Settings(c)
+		if err != nil {
+			return nil, err
+		}
+		total := runs + m.Warmup
+		for _, bm := range benchmarks {
+			item := PlanItem{
+				CRI:       c.CRI,
+				OCI:       c.OCI,
+				Benchmark: bm.Name(),
+				Params:    Params(bm),
+				Runs:      runs,
+				Warmup:    m.Warmup,
+				Launches:  total,
+				Source:    EstimateUnknown,
+			}
+			var info Info
+			if m.Registry != nil {
+				info, _ = m.Registry.Info(bm.Name())
+			}
+			if info.Launches > 0 {
+				item.Launches *= info.Launches
+			}
+			if runTime, ok := history.RunTime(c.CRI, c.OCI, bm.Name()); ok {
+				item.Estimate, item.Source = runTime.Seconds()*float64(total), EstimateResults
+			} else if info.Estimate > 0 {
+				item.Estimate, item.Source = info.Estimate.Seconds()*float64(total), EstimateRegistry
+			}
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+type historyKey struct {
+	cri, oci, name string
+}
+
+type runTimes struct {
+	total float64
+	count int
+}
+
+func (r *runTimes) add(seconds float64) {
+	r.total += seconds
+	r.count++
+}
+
+func (r runTimes) mean() time.Duration {
+	return time.Duration(r.total / float64(r.count) * float64(time.Second))
+}
+
+// History collects the run times of earlier results.
+type History struct {
+	exact  map[historyKey]*runTimes
+	byName map[string]*runTimes
+}
+
+// NewHistory creates an empty history.
+func NewHistory() *History {
+	return &History{
+		exact:  make(map[historyKey]*runTimes),
+		byName: make(map[string]*runTimes),
+	}
+}
+
+// Add records the run times of the entries. Results without run time are skipped.
+func (h *History) Add(entries []MatrixEntry) {
+	for _, entry := range entries {
+		for _, result := range entry.Results {
+			if result.RunTime <= 0 {
+				continue
+			}
+			key := historyKey{entry.CRI, entry.OCI, result.Name}
+			if h.exact[key] == nil {
+				h.exact[key] = &runTimes{}
+			}
+			if h.byName[result.Name] == nil {
+				h.byName[result.Name] = &runTimes{}
+			}
+			h.exact[key].add(result.RunTime)
+			h.byName[result.Name].add(result.RunTime)
+		}
+	}
+}
+
+// RunTime returns the mean run time of the benchmark for the CRI and handler.
+// It falls back to the mean over all CRIs and handlers. A nil history knows no run times.
+func (h *History) RunTime(cri, oci, name string) (time.Duration, bool) {
+	if h == nil {
+		return 0, false
+	}
+	if r, ok := h.exact[historyKey{cri, oci, name}]; ok {
+		return r.mean(), true
+	}
+	if r, ok := h.byName[name]; ok {
+		return r.mean(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type scaledBenchmark struct {
+	flakyBenchmark
+}
+
+func (bm *scaledBenchmark) Name() string { return "scaled" }
+func (bm *scaledBenchmark) Params() map[string]string {
+	return map[string]string{"scale": "5"}
+}
+
+func TestMatrixPlan(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(&flakyBenchmark{}, Info{Estimate: 10 * time.Second})
+	registry.MustRegister(&scaledBenchmark{}, Info{Estimate: 4 * time.Second, Launches: 5})
+	matrix := &Matrix{
+		CRIs:     []string{"containerd"},
+		OCIs:     []string{"runc", "runsc"},
+		Items:    registry.Benchmarks(),
+		Runs:     3,
+		Registry: registry,
+	}
+	history := NewHistory()
+	history.Add([]MatrixEntry{
+		{CRI: "containerd", OCI: "runsc", Results: []MatrixResult{{Name: "flaky", RunTime: 2}}},
+		{CRI: "crio", OCI: "runsc", Results: []MatrixResult{{Name: "flaky", RunTime: 4}}},
+	})
+	items, err := matrix.Plan(history)
+	if err != nil {
+		t.Fatalf("could not plan matrix: %v", err)
+	}
+	expected := []PlanItem{
+		{CRI: "containerd", OCI: "runc", Benchmark: "flaky", Runs: 3, Launches: 3, Estimate: 9, Source: EstimateResults},
+		{CRI: "containerd", OCI: "runc", Benchmark: "scaled", Params: map[string]string{"scale": "5"}, Runs: 3, Launches: 15, Estimate: 12, Source: EstimateRegistry},
+		{CRI: "containerd", OCI: "runsc", Benchmark: "flaky", Runs: 3, Launches: 3, Estimate: 6, Source: EstimateResults},
+		{CRI: "containerd", OCI: "runsc", Benchmark: "scaled", Params: map[string]string{"scale": "5"}, Runs: 3, Launches: 15, Estimate: 12, Source: EstimateRegistry},
+	}
+	if !reflect.DeepEqual(items, expected) {
+		t.Errorf("expected plan %+v, got %+v", expected, items)
+	}
+	if items, _ := (&Matrix{CRIs: []string{"containerd"}, OCIs: []string{"runc"}, Items: []Benchmark{&flakyBenchmark{}}, Runs: 1}).Plan(nil); items[0].Source != EstimateUnknown {
+		t.Errorf("expected unknown estimate without registry, got %+v", items[0])
+	}
+}
+
+func TestDecodeResults(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []MatrixEntry{{
+		CRI: "containerd",
+		OCI: "runc",
+		Results: []MatrixResult{{
+			Name:       "flaky",
+			Aggregated: ValueReport{"Run": 1},
+			Reports:    []Report{ValueReport{"Run": 0}, ValueReport{"Run": 2}},
+			RunTime:    1.5,
+		}},
+	}}
+	encoder := json.NewEncoder(&buf)
+	if err := encoder.Encode(Results{Entries: entries}); err != nil {
+		t.Fatalf("could not encode results: %v", err)
+	}
+	if err := encoder.Encode(entries); err != nil {
+		t.Fatalf("could not encode legacy results: %v", err)
+	}
+	results, err := DecodeResults(&buf)
+	if err != nil {
+		t.Fatalf("could not decode results: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(results))
+	}
+	for _, doc := range results {
+		if !reflect.DeepEqual(doc.Entries, entries) {
+			t.Errorf("expected entries %+v, got %+v", entries, doc.Entries)
+		}
+	}
+	if _, err := DecodeResults(strings.NewReader(`{"results": 5}`)); err == nil {
+		t.Errorf("expected error for malformed results")
+	}
+}
+
+func TestMatrixCombinations(t *testing.T) {
+	flaky, scaled := &flakyBenchmark{}, &scaledBenchmark{}
+	registry := NewRegistry()
+	registry.MustRegister(flaky, Info{})
+	registry.MustRegister(scaled, Info{})
+	matrix := &Matrix{
+		CRIs:      []string{"containerd", "crio"},
+		OCIs:      []string{"runc", "runsc"},
+		Items:     []Benchmark{flaky},
+		Runs:      3,
+		Registry:  registry,
+		Selection: Selection{Filter: []string{"flaky"}},
+		Exclude:   []Combination{{CRI: "crio", OCI: "runc"}},
+		Include:   []Combination{{CRI: "native", OCI: "host"}, {CRI: "crio", OCI: "runsc"}},
+		Overrides: []Override{
+			{Combination: Combination{OCI: "runsc"}, Runs: 1},
+			{Combination: Combination{CRI: "crio"}, Selection: Selection{Filter: []string{"flaky", "scaled"}}},
+			// only replaces the excludes, keeping the filter of the previous override
+			{Combination: Combination{CRI: "crio", OCI: "runsc"}, Selection: Selection{Exclude: []string{"flaky"}}},
+		},
+	}
+	expected := []Combination{
+		{CRI: "containerd", OCI: "runc"},
+		{CRI: "containerd", OCI: "runsc"},
+		{CRI: "crio", OCI: "runsc"},
+		{CRI: "native", OCI: "host"},
+	}
+	if combinations := matrix.Combinations(); !reflect.DeepEqual(combinations, expected) {
+		t.Errorf("expected combinations %v, got %v", expected, combinations)
+	}
+	tt := []struct {
+		Combination Combination
+		Items       []Benchmark
+		Runs        int
+	}{
+		{Combination{CRI: "containerd", OCI: "runc"}, []Benchmark{flaky}, 3},
+		{Combination{CRI: "containerd", OCI: "runsc"}, []Benchmark{flaky}, 1},
+		{Combination{CRI: "crio", OCI: "runsc"}, []Benchmark{scaled}, 1},
+		{Combination{CRI: "native", OCI: "host"}, []Benchmark{flaky}, 3},
+	}
+	for _, tc := range tt {
+		items, runs, err := matrix.Settings(tc.Combination)
+		if err != nil {
+			t.Fatalf("could not resolve settings of %s: %v", tc.Combination, err)
+		}
+		if !reflect.DeepEqual(items, tc.Items) || runs != tc.Runs {
+			t.Errorf("expected %d runs of %v for %s, got %d runs of %v", tc.Runs, tc.Items, tc.Combination, runs, items)
+		}
+	}
+}
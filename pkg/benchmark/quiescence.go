@@ -0,0 +1,105 @@
+package benchmark
+
+import (
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/environment"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultQuiescenceTimeout  = 5 * time.Minute
+	defaultQuiescenceInterval = time.Second
+	// cacheSensitiveTag marks benchmarks that get their page cache dropped before each run.
+	cacheSensitiveTag = "io"
+)
+
+// Quiescence configures the guards checked before each benchmark run to reduce host noise.
+// Zero thresholds disable the respective check.
+type Quiescence struct {
+	// MaxLoad is the highest tolerated 1-minute load average.
+	MaxLoad float64
+	// MaxCPU is the highest tolerated CPU utilization in percent.
+	MaxCPU float64
+	// Timeout limits the time spent waiting, after which the run starts anyway.
+	Timeout time.Duration
+	// Interval is the sampling period of the CPU utilization.
+	Interval time.Duration
+	// DropCaches drops the page cache before runs of IO benchmarks.
+	DropCaches bool
+}
+
+// HostState records the host conditions observed before a run.
+type HostState struct {
+	Run           int     `json:"run"`
+	Waited        float64 `json:"waited"`
+	Load          float64 `json:"load"`
+	CPU           float64 `json:"cpu"`
+	Quiet         bool    `json:"quiet"`
+	CachesDropped bool    `json:"cachesDropped,omitempty"`
+}
+
+// WarnGovernors logs a warning if any CPU is not using the performance governor.
+func WarnGovernors() {
+	governors, err := environment.Governors()
+	if err != nil {
+		logrus.WithError(err).Warn("failed to read cpu frequency governors")
+		return
+	}
+	for cpu, governor := range governors {
+		if governor != "performance" {
+			logrus.WithFields(logrus.Fields{
+				"cpu":      cpu,
+				"governor": governor,
+			}).Warn("cpu frequency governor is not performance, results may be noisy")
+			return
+		}
+	}
+}
+
+// Wait blocks until the host is quiet or the timeout passes.
+// It drops the page cache first if requested for the benchmark.
+func (q *Quiescence) Wait(bm Benchmark, run int) HostState {
+	state := HostState{Run: run}
+	if q.DropCaches && HasTag(bm, cacheSensitiveTag) {
+		if err := environment.DropCaches(); err != nil {
+			logrus.WithError(err).Warn("failed to drop caches")
+		} else {
+			state.CachesDropped = true
+		}
+	}
+	timeout, interval := q.Timeout, q.Interval
+	if timeout <= 0 {
+		timeout = defaultQuiescenceTimeout
+	}
+	if interval <= 0 {
+		interval = defaultQuiescenceInterval
+	}
+	start := time.Now()
+	for {
+		var err error
+		if state.Load, err = environment.LoadAverage(); err != nil {
+			logrus.WithError(err).Warn("failed to read load average")
+		}
+		if state.CPU, err = environment.CPUUtilization(interval); err != nil {
+			logrus.WithError(err).Warn("failed to read cpu utilization")
+		}
+		state.Quiet = (q.MaxLoad <= 0 || state.Load <= q.MaxLoad) && (q.MaxCPU <= 0 || state.CPU <= q.MaxCPU)
+		if state.Quiet || time.Since(start) >= timeout {
+			break
+		}
+		logrus.WithFields(logrus.Fields{
+			"load": state.Load,
+			"cpu":  state.CPU,
+		}).Debug("waiting for host to settle")
+	}
+	state.Waited = time.Since(start).Seconds()
+	if !state.Quiet {
+		logrus.WithFields(logrus.Fields{
+			"name": bm.Name(),
+			"load": state.Load,
+			"cpu":  state.CPU,
+		}).Warn("host did not settle before timeout")
+	}
+	return state
+}
@@ -0,0 +1,190 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Metric describes a label reported by a benchmark.
+type Metric struct {
+	Label          string `json:"label"`
+	Unit           string `json:"unit,omitempty"`
+	Description    string `json:"description,omitempty"`
+	HigherIsBetter bool   `json:"higherIsBetter,omitempty"`
+}
+
+// Info holds the metadata of a registered benchmark.
+type Info struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Tags        []string      `json:"tags,omitempty"`
+	Metrics     []Metric      `json:"metrics"`
+	Estimate    time.Duration `json:"estimate"`
+	// Launches is the number of containers started per run, 1 if unset.
+	Launches int `json:"launches,omitempty"`
+}
+
+type infoJSON Info
+
+// MarshalJSON encodes the estimate in seconds.
+func (info Info) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		infoJSON
+		Estimate float64 `json:"estimate"`
+	}{infoJSON(info), info.Estimate.Seconds()})
+}
+
+// UnmarshalJSON decodes the estimate from seconds.
+func (info *Info) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		infoJSON
+		Estimate float64 `json:"estimate"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*info = Info(decoded.infoJSON)
+	info.Estimate = time.Duration(decoded.Estimate * float64(time.Second))
+	return nil
+}
+
+// Metric returns the metadata of the given label.
+func (info Info) Metric(label string) (Metric, bool) {
+	for _, m := range info.Metrics {
+		if m.Label == label {
+			return m, true
+		}
+	}
+	return Metric{}, false
+}
+
+// Entry is a benchmark together with its metadata.
+type Entry struct {
+	Benchmark Benchmark
+	Info      Info
+}
+
+// Registry keeps track of all available benchmarks and their metadata.
+type Registry struct {
+	entries []Entry
+	byName  map[string]int
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byName: make(map[string]int),
+	}
+}
+
+// Register adds the benchmark to the registry. The name and tags of the metadata are taken from the benchmark.
+// Metrics must refer to labels of the benchmark; if none are given, they are derived from its labels.
+func (r *Registry) Register(bm Benchmark, info Info) error {
+	name := bm.Name()
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("benchmark %s already registered", name)
+	}
+	labels := make(map[string]bool)
+	for _, label := range bm.Labels() {
+		labels[label] = true
+	}
+	for _, m := range info.Metrics {
+		if !labels[m.Label] {
+			return fmt.Errorf("benchmark %s has no label %s", name, m.Label)
+		}
+	}
+	if len(info.Metrics) == 0 {
+		info.Metrics = r.deriveMetrics(bm)
+	}
+	if info.Launches == 0 {
+		info.Launches = r.deriveLaunches(bm)
+	}
+	info.Name = name
+	info.Tags = Tags(bm)
+	r.byName[name] = len(r.entries)
+	r.entries = append(r.entries, Entry{Benchmark: bm, Info: info})
+	return nil
+}
+
+// MustRegister adds the benchmark to the registry and panics on failure.
+func (r *Registry) MustRegister(bm Benchmark, info Info) {
+	if err := r.Register(bm, info); err != nil {
+		panic(err)
+	}
+}
+
+// deriveMetrics creates the metrics of a benchmark from its labels.
+// Suites reuse the metadata of their registered children.
+func (r *Registry) deriveMetrics(bm Benchmark) []Metric {
+	var metrics []Metric
+	if suite, ok := bm.(*Suite); ok {
+		for _, item := range suite.Items() {
+			info, known := r.Info(item.Name())
+			for _, label := range item.Labels() {
+				m := Metric{Label: label}
+				if known {
+					m, _ = info.Metric(label)
+				}
+				m.Label = SuiteLabel(item, label)
+				metrics = append(metrics, m)
+			}
+		}
+		return metrics
+	}
+	for _, label := range bm.Labels() {
+		metrics = append(metrics, Metric{Label: label})
+	}
+	return metrics
+}
+
+// deriveLaunches sums up the launches of the children of suites.
+func (r *Registry) deriveLaunches(bm Benchmark) int {
+	suite, ok := bm.(*Suite)
+	if !ok {
+		return 1
+	}
+	launches := 0
+	for _, item := range suite.Items() {
+		info, known := r.Info(item.Name())
+		if !known {
+			info.Launches = 1
+		}
+		launches += info.Launches
+	}
+	return launches
+}
+
+// Benchmarks returns all registered benchmarks in registration order.
+func (r *Registry) Benchmarks() []Benchmark {
+	items := make([]Benchmark, len(r.entries))
+	for i := range r.entries {
+		items[i] = r.entries[i].Benchmark
+	}
+	return items
+}
+
+// Entries returns all registered benchmarks together with their metadata.
+func (r *Registry) Entries() []Entry {
+	entries := make([]Entry, len(r.entries))
+	copy(entries, r.entries)
+	return entries
+}
+
+// Lookup finds a benchmark by name.
+func (r *Registry) Lookup(name string) (Benchmark, bool) {
+	i, ok := r.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return r.entries[i].Benchmark, true
+}
+
+// Info returns the metadata of the named benchmark.
+func (r *Registry) Info(name string) (Info, bool) {
+	i, ok := r.byName[name]
+	if !ok {
+		return Info{}, false
+	}
+	return r.entries[i].Info, true
+}
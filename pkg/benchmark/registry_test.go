@@ -0,0 +1,61 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type labeledBenchmark struct {
+	fakeBenchmark
+	labels []string
+}
+
+func (bm *labeledBenchmark) Labels() []string { return bm.labels }
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	cpu := &labeledBenchmark{fakeBenchmark{"performance.cpu.time", []string{"cpu"}}, []string{"TotalTime"}}
+	if err := registry.Register(cpu, Info{
+		Description: "CPU time",
+		Metrics:     []Metric{{Label: "TotalTime", Unit: "s"}},
+		Estimate:    30 * time.Second,
+	}); err != nil {
+		t.Fatalf("could not register benchmark: %v", err)
+	}
+	if err := registry.Register(cpu, Info{}); err == nil {
+		t.Errorf("expected duplicate registration to fail")
+	}
+	unknown := &labeledBenchmark{fakeBenchmark{"performance.memory.total", nil}, []string{"TotalTime"}}
+	if err := registry.Register(unknown, Info{Metrics: []Metric{{Label: "Throughput"}}}); err == nil {
+		t.Errorf("expected unknown metric label to fail")
+	}
+	suite := NewSuite("composite.cpu", cpu)
+	if err := registry.Register(suite, Info{}); err != nil {
+		t.Fatalf("could not register suite: %v", err)
+	}
+	info, ok := registry.Info("composite.cpu")
+	if !ok {
+		t.Fatalf("expected suite to be registered")
+	}
+	expected := []Metric{{Label: "performance.cpu.time/TotalTime", Unit: "s"}}
+	if !reflect.DeepEqual(info.Metrics, expected) || !reflect.DeepEqual(info.Tags, []string{"cpu"}) {
+		t.Errorf("expected derived metadata, got %+v", info)
+	}
+	if names := len(registry.Benchmarks()); names != 2 {
+		t.Errorf("expected 2 benchmarks, got %d", names)
+	}
+	cpuInfo, _ := registry.Info("performance.cpu.time")
+	data, err := json.Marshal(cpuInfo)
+	if err != nil {
+		t.Fatalf("could not marshal info: %v", err)
+	}
+	var decoded Info
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("could not unmarshal info: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, cpuInfo) {
+		t.Errorf("expected %+v, got %+v", cpuInfo, decoded)
+	}
+}
@@ -0,0 +1,161 @@
+package benchmark
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// ResultsVersion is the version of the result document written by this release.
+// The JSON Schema of each version is published in schema/results.v<version>.json.
+const ResultsVersion = 1
+
+// migrations upgrade a result document of version i to version i+1.
+var migrations = []func(doc map[string]json.RawMessage) error{
+	// Version 0 documents lack the version only.
+	func(doc map[string]json.RawMessage) error { return nil },
+}
+
+// upgrade migrates a result document to the latest version. Legacy documents, a bare list of
+// matrix entries without environment, and documents without version are version 0.
+func upgrade(raw json.RawMessage) (map[string]json.RawMessage, error) {
+	doc := make(map[string]json.RawMessage)
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		doc["results"] = raw
+	} else if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	var version int
+	if v, ok := doc["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("invalid results version: %v", err)
+		}
+	}
+	if version < 0 || version > ResultsVersion {
+		return nil, fmt.Errorf("unsupported results version %d, this release reads up to version %d", version, ResultsVersion)
+	}
+	for ; version < ResultsVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, fmt.Errorf("failed to upgrade results from version %d: %v", version, err)
+		}
+	}
+	doc["version"] = json.RawMessage(strconv.Itoa(ResultsVersion))
+	return doc, nil
+}
+
+// DecodeResults reads all results from a benchmark output file and upgrades them to the latest version.
+// Files may hold several appended documents.
+func DecodeResults(r io.Reader) ([]Results, error) {
+	var (
+		decoder = json.NewDecoder(r)
+		results []Results
+	)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		doc, err := upgrade(raw)
+		if err != nil {
+			return nil, err
+		}
+		upgraded, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var decoded Results
+		if err := json.Unmarshal(upgraded, &decoded); err != nil {
+			return nil, err
+		}
+		results = append(results, decoded)
+	}
+}
+
+type matrixResultJSON MatrixResult
+
+// UnmarshalJSON decodes the reports as value reports.
+func (result *MatrixResult) UnmarshalJSON(data []byte) error {
+	var decoded struct {
+		matrixResultJSON
+		Aggregated ValueReport   `json:"aggregated"`
+		Reports    []ValueReport `json:"reports"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*result = MatrixResult(decoded.matrixResultJSON)
+	if decoded.Aggregated != nil {
+		result.Aggregated = decoded.Aggregated
+	}
+	result.Reports = make([]Report, len(decoded.Reports))
+	for i, report := range decoded.Reports {
+		result.Reports[i] = report
+	}
+	return nil
+}
+
+// MergeEntries combines the results of entries with the same CRI and handler, keeping their order.
+func MergeEntries(entries []MatrixEntry) []MatrixEntry {
+	var merged []MatrixEntry
+	positions := make(map[Combination]int)
+	for _, entry := range entries {
+		c := Combination{CRI: entry.CRI, OCI: entry.OCI}
+		if i, ok := positions[c]; ok {
+			merged[i].Results = append(merged[i].Results, entry.Results...)
+			continue
+		}
+		positions[c] = len(merged)
+		merged = append(merged, MatrixEntry{
+			CRI:     entry.CRI,
+			OCI:     entry.OCI,
+			Results: append([]MatrixResult{}, entry.Results...),
+		})
+	}
+	return merged
+}
+
+// AddResults indexes the benchmarks of saved results. Labels and descriptions are taken from the registry,
+// labels reported but unknown to the registry, like those of benchmarks derived by params, are appended.
+func (index Index) AddResults(entries []MatrixEntry, registry *Registry) {
+	for _, entry := range entries {
+		for _, result := range entry.Results {
+			indexEntry, ok := index[result.Name]
+			if !ok {
+				indexEntry = IndexEntry{Datasets: make([]int, 0)}
+				if registry != nil {
+					if bm, ok := registry.Lookup(result.Name); ok {
+						info, _ := registry.Info(result.Name)
+						indexEntry.Labels = append([]string{}, bm.Labels()...)
+						indexEntry.Description = info.Description
+					}
+				}
+			}
+			var reported []string
+			for _, report := range append([]Report{result.Aggregated}, result.Reports...) {
+				values, _ := report.(ValueReport)
+				for label := range values {
+					if !containsLabel(indexEntry.Labels, label) && !containsLabel(reported, label) {
+						reported = append(reported, label)
+					}
+				}
+			}
+			sort.Strings(reported)
+			indexEntry.Labels = append(indexEntry.Labels, reported...)
+			index[result.Name] = indexEntry
+		}
+	}
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
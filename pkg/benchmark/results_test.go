@@ -0,0 +1,118 @@
+package benchmark
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/environment"
+)
+
+// TestResultsSchema checks that the published schema describes the fields of the result types.
+func TestResultsSchema(t *testing.T) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("../../schema/results.v%d.json", ResultsVersion))
+	if err != nil {
+		t.Fatalf("could not read schema: %v", err)
+	}
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	var schema struct {
+		object
+		Definitions map[string]object `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+	types := map[string]interface{}{
+		"":          Results{},
+		"manifest":  environment.Manifest{},
+		"host":      environment.Host{},
+		"runtime":   environment.Runtime{},
+		"condition": environment.Condition{},
+		"handler":   environment.Handler{},
+		"entry":     MatrixEntry{},
+		"result":    MatrixResult{},
+		"failure":   Failure{},
+		"hostState": HostState{},
+	}
+	for name, value := range types {
+		properties := schema.Properties
+		if name != "" {
+			properties = schema.Definitions[name].Properties
+		}
+		var expected, got []string
+		typ := reflect.TypeOf(value)
+		for i := 0; i < typ.NumField(); i++ {
+			expected = append(expected, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		for key := range properties {
+			got = append(got, key)
+		}
+		sort.Strings(expected)
+		sort.Strings(got)
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("expected schema of %s to have properties %v, got %v", typ, expected, got)
+		}
+	}
+}
+
+func TestUpgradeResults(t *testing.T) {
+	tt := []struct {
+		Name  string
+		Input string
+		Error bool
+	}{
+		{"legacy", `[{"cri": "containerd", "oci": "runc", "results": []}]`, false},
+		{"unversioned", `{"environment": null, "results": [{"cri": "containerd", "oci": "runc", "results": []}]}`, false},
+		{"current", `{"version": 1, "environment": null, "results": [{"cri": "containerd", "oci": "runc", "results": []}]}`, false},
+		{"future", `{"version": 2, "results": []}`, true},
+		{"invalid", `{"version": "one"}`, true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			results, err := DecodeResults(strings.NewReader(tc.Input))
+			if tc.Error {
+				if err == nil {
+					t.Errorf("expected error, got %+v", results)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not decode results: %v", err)
+			}
+			if len(results) != 1 || results[0].Version != ResultsVersion {
+				t.Fatalf("expected a document of version %d, got %+v", ResultsVersion, results)
+			}
+			if entries := results[0].Entries; len(entries) != 1 || entries[0].CRI != "containerd" {
+				t.Errorf("unexpected entries %+v", entries)
+			}
+		})
+	}
+}
+
+func TestIndexResults(t *testing.T) {
+	registry := NewRegistry()
+	registry.MustRegister(&flakyBenchmark{}, Info{Description: "Fails every second run."})
+	entries := MergeEntries([]MatrixEntry{
+		{CRI: "containerd", OCI: "runc", Results: []MatrixResult{{Name: "flaky", Aggregated: ValueReport{"Run": 1, "Extra": 2}}}},
+		{CRI: "crio", OCI: "runc", Results: []MatrixResult{{Name: "derived", Reports: []Report{ValueReport{"B": 1, "A": 2}}}}},
+		{CRI: "containerd", OCI: "runc", Results: []MatrixResult{{Name: "derived"}}},
+	})
+	if len(entries) != 2 || len(entries[0].Results) != 2 {
+		t.Fatalf("expected 2 merged entries, got %+v", entries)
+	}
+	index := NewIndex()
+	index.AddResults(entries, registry)
+	expected := Index{
+		"flaky":   {Description: "Fails every second run.", Labels: []string{"Run", "Extra"}, Datasets: []int{}},
+		"derived": {Labels: []string{"A", "B"}, Datasets: []int{}},
+	}
+	if !reflect.DeepEqual(index, expected) {
+		t.Errorf("expected index %+v, got %+v", expected, index)
+	}
+}
@@ -0,0 +1,188 @@
+package benchmark
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Tagged is implemented by benchmarks that declare tags like "cpu", "io", "startup" or "slow".
+type Tagged interface {
+	Tags() []string
+}
+
+// Tags returns the tags declared by the benchmark.
+func Tags(bm Benchmark) []string {
+	if tagged, ok := bm.(Tagged); ok {
+		return tagged.Tags()
+	}
+	return nil
+}
+
+// HasTag checks if the benchmark declares the given tag.
+func HasTag(bm Benchmark, tag string) bool {
+	for _, t := range Tags(bm) {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// term matches a single property of a benchmark.
+type term struct {
+	negate bool
+	match  func(Benchmark) bool
+}
+
+// expression is a list of terms. It matches if any positive term matches
+// (or there are none) and none of the negated terms match.
+type expression []term
+
+func (e expression) matches(bm Benchmark) bool {
+	positive, matched := false, false
+	for _, t := range e {
+		ok := t.match(bm)
+		if t.negate && ok {
+			return false
+		}
+		if !t.negate {
+			positive = true
+			matched = matched || ok
+		}
+	}
+	return matched || !positive
+}
+
+// parseExpression parses a comma-separated list of terms. Each term may be negated with a leading '!'
+// and selects its kind with a 'name:', 'tag:' or 're:' prefix. Terms without a prefix inherit the
+// kind of the previous term and default to names. Names are matched as globs if they contain
+// any of '*?[', otherwise as prefixes. For example 'tag:startup,!slow' selects all startup
+// benchmarks that are not slow and 'performance.*,!performance.disk.*' all performance
+// benchmarks except the disk ones.
+func parseExpression(expr string) (expression, error) {
+	var (
+		result expression
+		kind   = "name"
+	)
+	for _, raw := range strings.Split(expr, ",") {
+		raw = strings.TrimSpace(raw)
+		negate := strings.HasPrefix(raw, "!")
+		raw = strings.TrimPrefix(raw, "!")
+		for _, prefix := range []string{"name", "tag", "re"} {
+			if strings.HasPrefix(raw, prefix+":") {
+				kind = prefix
+				raw = strings.TrimPrefix(raw, prefix+":")
+				break
+			}
+		}
+		if raw == "" {
+			return nil, fmt.Errorf("empty term in expression %q", expr)
+		}
+		match, err := compileTerm(kind, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression %q: %v", expr, err)
+		}
+		result = append(result, term{negate: negate, match: match})
+	}
+	return result, nil
+}
+
+func compileTerm(kind, pattern string) (func(Benchmark) bool, error) {
+	switch kind {
+	case "tag":
+		return func(bm Benchmark) bool {
+			return HasTag(bm, pattern)
+		}, nil
+	case "re":
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(bm Benchmark) bool {
+			return re.MatchString(bm.Name())
+		}, nil
+	}
+	if !strings.ContainsAny(pattern, "*?[") {
+		return func(bm Benchmark) bool {
+			return strings.HasPrefix(bm.Name(), pattern)
+		}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	return func(bm Benchmark) bool {
+		ok, _ := path.Match(pattern, bm.Name())
+		return ok
+	}, nil
+}
+
+// Selector picks benchmarks by include and exclude expressions.
+type Selector struct {
+	include, exclude []expression
+}
+
+// NewSelector parses the include and exclude expressions. A benchmark is selected if it matches
+// any include expression, or there are none, and no exclude expression.
+func NewSelector(include, exclude []string) (*Selector, error) {
+	s := &Selector{}
+	for _, expr := range include {
+		e, err := parseExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		s.include = append(s.include, e)
+	}
+	for _, expr := range exclude {
+		e, err := parseExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		s.exclude = append(s.exclude, e)
+	}
+	return s, nil
+}
+
+// Match checks if the benchmark is selected.
+func (s *Selector) Match(bm Benchmark) bool {
+	for _, e := range s.exclude {
+		if e.matches(bm) {
+			return false
+		}
+	}
+	if len(s.include) == 0 {
+		return true
+	}
+	for _, e := range s.include {
+		if e.matches(bm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns a new slice of the selected benchmarks, leaving items untouched.
+func (s *Selector) Select(items []Benchmark) []Benchmark {
+	logrus.WithField("itemCount", len(items)).Debug("filtering items")
+	selected := make([]Benchmark, 0, len(items))
+	for _, item := range items {
+		if s.Match(item) {
+			selected = append(selected, item)
+		} else {
+			logrus.WithField("name", item.Name()).Debug("filtered item")
+		}
+	}
+	return selected
+}
+
+// Filter selects the benchmarks matching the include and exclude expressions.
+func Filter(items []Benchmark, include, exclude []string) ([]Benchmark, error) {
+	s, err := NewSelector(include, exclude)
+	if err != nil {
+		return nil, err
+	}
+	return s.Select(items), nil
+}
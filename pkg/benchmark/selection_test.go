@@ -0,0 +1,70 @@
+package benchmark
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+)
+
+type fakeBenchmark struct {
+	name string
+	tags []string
+}
+
+func (bm *fakeBenchmark) Name() string     { return bm.name }
+func (bm *fakeBenchmark) Labels() []string { return nil }
+func (bm *fakeBenchmark) Tags() []string   { return bm.tags }
+func (bm *fakeBenchmark) Run(client runtime.Runtime, handler string) (Report, error) {
+	return ValueReport{}, nil
+}
+
+func TestFilter(t *testing.T) {
+	items := []Benchmark{
+		&fakeBenchmark{"performance.cpu.time", []string{"cpu"}},
+		&fakeBenchmark{"performance.disk.read", []string{"io", "slow"}},
+		&fakeBenchmark{"operations.container.lifecycle", []string{"startup"}},
+		&fakeBenchmark{"scalability.runtime.50", []string{"startup", "slow"}},
+	}
+	tt := []struct {
+		Name             string
+		Include, Exclude []string
+		Selected         []string
+	}{
+		{"all", nil, nil, []string{"performance.cpu.time", "performance.disk.read", "operations.container.lifecycle", "scalability.runtime.50"}},
+		{"prefix", []string{"performance"}, nil, []string{"performance.cpu.time", "performance.disk.read"}},
+		{"glob", []string{"*.cpu.*"}, nil, []string{"performance.cpu.time"}},
+		{"regexp", []string{`re:^(operations|scalability)\.`}, nil, []string{"operations.container.lifecycle", "scalability.runtime.50"}},
+		{"tags", []string{"tag:startup,!slow"}, nil, []string{"operations.container.lifecycle"}},
+		{"any-of", []string{"performance.cpu,operations"}, nil, []string{"performance.cpu.time", "operations.container.lifecycle"}},
+		{"exclude", []string{"performance.*"}, []string{"tag:io"}, []string{"performance.cpu.time"}},
+		{"negated-only", []string{"!tag:slow"}, nil, []string{"performance.cpu.time", "operations.container.lifecycle"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			before := append([]Benchmark(nil), items...)
+			selected, err := Filter(items, tc.Include, tc.Exclude)
+			if err != nil {
+				t.Fatalf("could not filter items: %v", err)
+			}
+			names := make([]string, len(selected))
+			for i := range selected {
+				names[i] = selected[i].Name()
+			}
+			if !reflect.DeepEqual(names, tc.Selected) {
+				t.Errorf("expected %v, got %v", tc.Selected, names)
+			}
+			if !reflect.DeepEqual(items, before) {
+				t.Errorf("expected items to be untouched")
+			}
+		})
+	}
+}
+
+func TestFilterInvalid(t *testing.T) {
+	for _, expr := range []string{"re:(", "perf[", "tag:io,"} {
+		if _, err := Filter(nil, []string{expr}, nil); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
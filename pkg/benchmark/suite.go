@@ -0,0 +1,229 @@
+package benchmark
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/sirupsen/logrus"
+)
+
+// Sandboxed is implemented by benchmarks that can share a pod sandbox with other benchmarks.
+type Sandboxed interface {
+	Benchmark
+	Imaged
+	RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (Report, error)
+}
+
+// RunSandboxed runs the benchmark in a fresh pod sandbox.
+func RunSandboxed(bm Sandboxed, client runtime.Runtime, handler string) (Report, error) {
+	sandbox, err := runtime.RunSandbox(client, ID(bm), handler)
+	if err != nil {
+		return nil, err
+	}
+	report, err := bm.RunInSandbox(client, sandbox)
+	if err != nil {
+		if rmErr := runtime.StopAndRemoveSandbox(client, sandbox.ID); rmErr != nil {
+			logrus.WithError(rmErr).WithField("pod", sandbox.ID).Warn("failed to remove sandbox")
+		}
+		return nil, err
+	}
+	if err := runtime.StopAndRemoveSandbox(client, sandbox.ID); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// Suite is a composite benchmark. Its children share image pulls and a single pod sandbox
+// where possible, and their reports are merged with labels namespaced by the child name.
+// The shared sandbox is created before each run, so sandboxed children do not receive run hooks.
+type Suite struct {
+	name    string
+	items   []Benchmark
+	sandbox *runtime.Sandbox
+}
+
+// NewSuite creates a composite benchmark from the given children.
+func NewSuite(name string, items ...Benchmark) *Suite {
+	return &Suite{name: name, items: items}
+}
+
+func (bs *Suite) Name() string {
+	return bs.name
+}
+
+// Items returns the children of the suite.
+func (bs *Suite) Items() []Benchmark {
+	return bs.items
+}
+
+// Labels returns the labels of all children prefixed by their name.
+func (bs *Suite) Labels() []string {
+	var labels []string
+	for _, item := range bs.items {
+		for _, label := range item.Labels() {
+			labels = append(labels, SuiteLabel(item, label))
+		}
+	}
+	return labels
+}
+
+// Tags returns the tags of all children.
+func (bs *Suite) Tags() []string {
+	var (
+		tags []string
+		seen = make(map[string]bool)
+	)
+	for _, item := range bs.items {
+		for _, tag := range Tags(item) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// Images returns the images used by any of the children.
+func (bs *Suite) Images() []string {
+	var (
+		images []string
+		seen   = make(map[string]bool)
+	)
+	for _, item := range bs.items {
+		imaged, ok := item.(Imaged)
+		if !ok {
+			continue
+		}
+		for _, image := range imaged.Images() {
+			if !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
+
+// Setup calls the entry setup hooks of all children.
+// If a hook fails, the children set up before are torn down in reverse order.
+func (bs *Suite) Setup(ctx context.Context, client runtime.Runtime, handler string) error {
+	for i, item := range bs.items {
+		if hooks, ok := item.(EntryHooks); ok {
+			if err := hooks.Setup(ctx, client, handler); err != nil {
+				rollback(bs.items[:i], func(item Benchmark) error {
+					return Teardown(ctx, item, client, handler)
+				})
+				return fmt.Errorf("failed to setup %s: %w", item.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Teardown calls the entry teardown hooks of all children.
+func (bs *Suite) Teardown(ctx context.Context, client runtime.Runtime, handler string) error {
+	for _, item := range bs.items {
+		if err := Teardown(ctx, item, client, handler); err != nil {
+			return fmt.Errorf("failed to teardown %s: %w", item.Name(), err)
+		}
+	}
+	return nil
+}
+
+// SetupRun creates the shared sandbox and calls the run setup hooks of all children not using it.
+// If a hook fails, the children set up before are torn down in reverse order and the sandbox is removed.
+func (bs *Suite) SetupRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	sandbox, err := runtime.RunSandbox(client, ID(bs), handler)
+	if err != nil {
+		return err
+	}
+	bs.sandbox = sandbox
+	for i, item := range bs.items {
+		if _, ok := item.(Sandboxed); ok {
+			continue
+		}
+		if err := SetupRun(ctx, item, client, handler); err != nil {
+			rollback(bs.items[:i], func(item Benchmark) error {
+				if _, ok := item.(Sandboxed); ok {
+					return nil
+				}
+				return TeardownRun(ctx, item, client, handler)
+			})
+			bs.sandbox = nil
+			if rmErr := runtime.StopAndRemoveSandbox(client, sandbox.ID); rmErr != nil {
+				logrus.WithError(rmErr).WithField("pod", sandbox.ID).Warn("failed to remove sandbox")
+			}
+			return fmt.Errorf("failed to setup %s: %w", item.Name(), err)
+		}
+	}
+	return nil
+}
+
+// TeardownRun calls the run teardown hooks of all children not using the shared sandbox and removes it.
+func (bs *Suite) TeardownRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	for _, item := range bs.items {
+		if _, ok := item.(Sandboxed); ok {
+			continue
+		}
+		if err := TeardownRun(ctx, item, client, handler); err != nil {
+			return fmt.Errorf("failed to teardown %s: %w", item.Name(), err)
+		}
+	}
+	if bs.sandbox == nil {
+		return nil
+	}
+	pod := bs.sandbox.ID
+	bs.sandbox = nil
+	return runtime.StopAndRemoveSandbox(client, pod)
+}
+
+// rollback tears down the children in reverse order, logging instead of returning errors.
+func rollback(items []Benchmark, teardown func(Benchmark) error) {
+	for i := len(items) - 1; i >= 0; i-- {
+		if err := teardown(items[i]); err != nil {
+			logrus.WithError(err).WithField("name", items[i].Name()).Warn("failed to teardown after failed setup")
+		}
+	}
+}
+
+// Run runs the children in the shared sandbox, or in a fresh one if there is none.
+func (bs *Suite) Run(client runtime.Runtime, handler string) (Report, error) {
+	if bs.sandbox != nil {
+		return bs.RunInSandbox(client, bs.sandbox)
+	}
+	return RunSandboxed(bs, client, handler)
+}
+
+// RunInSandbox runs all sandboxed children in the given sandbox and all other children on their own.
+func (bs *Suite) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (Report, error) {
+	merged := ValueReport{}
+	for _, item := range bs.items {
+		var (
+			report Report
+			err    error
+		)
+		if sandboxed, ok := item.(Sandboxed); ok {
+			report, err = sandboxed.RunInSandbox(client, sandbox)
+		} else {
+			report, err = item.Run(client, sandbox.Handler)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to run suite %s: %w", item.Name(), err)
+		}
+		values, ok := report.(ValueReport)
+		if !ok {
+			return nil, fmt.Errorf("failed to merge report of %s: unsupported report type %T", item.Name(), report)
+		}
+		for label, value := range values {
+			merged[SuiteLabel(item, label)] = value
+		}
+	}
+	return merged, nil
+}
+
+// SuiteLabel namespaces a label by the name of the child benchmark.
+func SuiteLabel(item Benchmark, label string) string {
+	return item.Name() + "/" + label
+}
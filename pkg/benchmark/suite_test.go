@@ -0,0 +1,99 @@
+package benchmark
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// sandboxRuntime records the sandboxes started and removed.
+type sandboxRuntime struct {
+	fakeRuntime
+	started, removed []string
+}
+
+func (rt *sandboxRuntime) StartSandbox(sandbox *runtimeapi.PodSandboxConfig, handler string) (string, error) {
+	rt.started = append(rt.started, sandbox.Metadata.Name)
+	return sandbox.Metadata.Name, nil
+}
+
+func (rt *sandboxRuntime) StopSandbox(pod string) error { return nil }
+
+func (rt *sandboxRuntime) RemoveSandbox(pod string) error {
+	rt.removed = append(rt.removed, pod)
+	return nil
+}
+
+// hookBenchmark records its hook calls in a shared log and fails its setup hooks if asked to.
+type hookBenchmark struct {
+	name string
+	fail bool
+	log  *[]string
+}
+
+func (bm *hookBenchmark) Name() string     { return bm.name }
+func (bm *hookBenchmark) Labels() []string { return nil }
+func (bm *hookBenchmark) Run(client runtime.Runtime, handler string) (Report, error) {
+	return ValueReport{}, nil
+}
+
+func (bm *hookBenchmark) call(hook string, fail bool) error {
+	*bm.log = append(*bm.log, bm.name+"."+hook)
+	if fail {
+		return errors.New("setup failed")
+	}
+	return nil
+}
+
+func (bm *hookBenchmark) Setup(ctx context.Context, client runtime.Runtime, handler string) error {
+	return bm.call("Setup", bm.fail)
+}
+
+func (bm *hookBenchmark) Teardown(ctx context.Context, client runtime.Runtime, handler string) error {
+	return bm.call("Teardown", false)
+}
+
+func (bm *hookBenchmark) SetupRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	return bm.call("SetupRun", bm.fail)
+}
+
+func (bm *hookBenchmark) TeardownRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	return bm.call("TeardownRun", false)
+}
+
+func TestSuiteSetupRollback(t *testing.T) {
+	var log []string
+	suite := NewSuite("suite",
+		&hookBenchmark{name: "a", log: &log},
+		&hookBenchmark{name: "b", log: &log},
+		&hookBenchmark{name: "c", fail: true, log: &log},
+		&hookBenchmark{name: "d", log: &log},
+	)
+	client := &sandboxRuntime{}
+	if err := suite.Setup(context.Background(), client, "runc"); err == nil {
+		t.Fatalf("expected setup to fail")
+	}
+	expected := []string{"a.Setup", "b.Setup", "c.Setup", "b.Teardown", "a.Teardown"}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected hook calls %v, got %v", expected, log)
+	}
+
+	log = nil
+	if err := suite.SetupRun(context.Background(), client, "runc"); err == nil {
+		t.Fatalf("expected run setup to fail")
+	}
+	expected = []string{"a.SetupRun", "b.SetupRun", "c.SetupRun", "b.TeardownRun", "a.TeardownRun"}
+	if !reflect.DeepEqual(log, expected) {
+		t.Errorf("expected hook calls %v, got %v", expected, log)
+	}
+	if !reflect.DeepEqual(client.started, client.removed) || len(client.removed) != 1 {
+		t.Errorf("expected shared sandbox to be removed, started %v, removed %v", client.started, client.removed)
+	}
+	if suite.sandbox != nil {
+		t.Errorf("expected no shared sandbox after failed run setup")
+	}
+}
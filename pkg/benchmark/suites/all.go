@@ -2,10 +2,20 @@ package suites
 
 import "github.com/lnsp/touchstone/pkg/benchmark"
 
-func All() (All []benchmark.Benchmark) {
-	All = append(All, Performance...)
-	All = append(All, Operations...)
-	All = append(All, Scalability...)
-	All = append(All, Limits...)
-	return All
+// Registry holds all benchmarks shipped with touchstone.
+var Registry = newRegistry(Performance, Operations, Scalability, Limits, Composites)
+
+func newRegistry(groups ...[]benchmark.Entry) *benchmark.Registry {
+	registry := benchmark.NewRegistry()
+	for _, group := range groups {
+		for _, entry := range group {
+			registry.MustRegister(entry.Benchmark, entry.Info)
+		}
+	}
+	return registry
+}
+
+// All returns all registered benchmarks.
+func All() []benchmark.Benchmark {
+	return Registry.Benchmarks()
 }
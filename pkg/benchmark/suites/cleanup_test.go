@@ -0,0 +1,87 @@
+package suites
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/runtime"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// leakRuntime tracks the sandboxes and containers left behind and fails the nth container create or start.
+type leakRuntime struct {
+	runtime.Runtime
+	failCreate, failStart int
+	creates, starts       int
+	pods, containers      map[string]bool
+}
+
+func newLeakRuntime(failCreate, failStart int) *leakRuntime {
+	return &leakRuntime{
+		failCreate: failCreate,
+		failStart:  failStart,
+		pods:       make(map[string]bool),
+		containers: make(map[string]bool),
+	}
+}
+
+func (rt *leakRuntime) StartSandbox(sandbox *runtimeapi.PodSandboxConfig, handler string) (string, error) {
+	rt.pods[sandbox.Metadata.Name] = true
+	return sandbox.Metadata.Name, nil
+}
+
+func (rt *leakRuntime) StopSandbox(pod string) error { return nil }
+
+func (rt *leakRuntime) RemoveSandbox(pod string) error {
+	delete(rt.pods, pod)
+	return nil
+}
+
+func (rt *leakRuntime) CreateContainerWithOptions(sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, opts runtime.ContainerOptions) (string, error) {
+	rt.creates++
+	if rt.creates == rt.failCreate {
+		return "", errors.New("create failed")
+	}
+	rt.containers[name] = true
+	return name, nil
+}
+
+func (rt *leakRuntime) StartContainer(container string) error {
+	rt.starts++
+	if rt.starts == rt.failStart {
+		return errors.New("start failed")
+	}
+	return nil
+}
+
+func (rt *leakRuntime) StopContainer(container string, timeout int) error { return nil }
+
+func (rt *leakRuntime) RemoveContainer(container string) error {
+	delete(rt.containers, container)
+	return nil
+}
+
+func TestCleanupAfterFailure(t *testing.T) {
+	tt := []struct {
+		Name                  string
+		Benchmark             benchmark.Benchmark
+		FailCreate, FailStart int
+	}{
+		{"lifecycle create", &ContainerLifecycle{}, 1, 0},
+		{"lifecycle start", &ContainerLifecycle{}, 0, 1},
+		{"scalability create", &StartupScalability{Scale: 3}, 2, 0},
+		{"scalability start", &StartupScalability{Scale: 3}, 0, 3},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			client := newLeakRuntime(tc.FailCreate, tc.FailStart)
+			if _, err := tc.Benchmark.Run(client, "runc"); err == nil {
+				t.Fatalf("expected run to fail")
+			}
+			if len(client.pods) > 0 || len(client.containers) > 0 {
+				t.Errorf("expected no leftovers, got pods %v, containers %v", client.pods, client.containers)
+			}
+		})
+	}
+}
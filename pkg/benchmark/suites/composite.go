@@ -0,0 +1,17 @@
+package suites
+
+import (
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+)
+
+var Composites = []benchmark.Entry{
+	{
+		Benchmark: benchmark.NewSuite("composite.sysbench", &CPUTime{}, &MemoryTime{}, &DiskRead{}, &DiskWrite{}),
+		Info: benchmark.Info{
+			Description: "CPU, memory and disk sysbench workloads sharing a single sandbox.",
+			Estimate:    3 * time.Minute,
+		},
+	},
+}
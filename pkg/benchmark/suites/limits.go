@@ -5,68 +5,40 @@ import (
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
 	"github.com/lnsp/touchstone/pkg/runtime"
-	"github.com/lnsp/touchstone/pkg/util"
 	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
-var Limits = []benchmark.Benchmark{
-	&CPULimits{},
-	&CPUScalingLimits{},
+var Limits = []benchmark.Entry{
+	{
+		Benchmark: &CPULimits{},
+		Info: benchmark.Info{
+			Description: "Time and event rate of verifying primes up to 20000 with a CPU quota of 10%.",
+			Metrics:     cpuLimitsMetrics,
+			Estimate:    5 * time.Minute,
+		},
+	},
+	{
+		Benchmark: &CPUScalingLimits{},
+		Info: benchmark.Info{
+			Description: "Time and event rate of verifying primes up to 20000 while raising the CPU quota from 55% to 100%.",
+			Metrics:     cpuLimitsMetrics,
+			Estimate:    45 * time.Second,
+		},
+	},
 }
 
-// RunInSysbench executes a specific sysbench benchmark and returns the application logs.
-func RunInSysbenchWithResources(bm benchmark.Benchmark, client *runtime.Client, handler string, args []string, resources *runtimeapi.LinuxContainerResources) ([]byte, error) {
-	var (
-		sandboxID   = benchmark.ID(bm)
-		containerID = benchmark.ID(bm)
-	)
-	// Pull image
-	if err := client.PullImage(defaultSysbenchImage, nil); err != nil {
-		return nil, err
-	}
-	// Perform benchmark
-	sandbox := client.InitLinuxSandbox(sandboxID)
-	pod, err := client.StartSandbox(sandbox, handler)
-	if err != nil {
-		return nil, err
-	}
-	container, err := client.CreateContainerWithResources(sandbox, pod, containerID, defaultSysbenchImage, args, resources)
-	if err != nil {
-		return nil, err
-	}
-	if err := client.StartContainer(container); err != nil {
-		return nil, err
-	}
-	logs, err := client.WaitForLogs(container)
-	if err != nil {
-		return nil, err
-	}
-	// Cleanup container and sandbox
-	if err := client.StopAndRemoveContainer(container); err != nil {
-		return nil, err
-	}
-	if err := client.StopAndRemoveSandbox(pod); err != nil {
-		return nil, err
-	}
-	return logs, nil
+var cpuLimitsMetrics = []benchmark.Metric{
+	metricTotalTime,
+	metricEventsPerSecond,
+	metricAvgLatency,
+	metricP95Latency,
 }
 
-func RunInSysbenchWithScalingResources(bm benchmark.Benchmark, client *runtime.Client, handler string, args []string, resources []*runtimeapi.LinuxContainerResources, interval time.Duration) ([]byte, error) {
-	var (
-		sandboxID   = benchmark.ID(bm)
-		containerID = benchmark.ID(bm)
-	)
-	// Pull image
-	if err := client.PullImage(defaultSysbenchImage, nil); err != nil {
-		return nil, err
-	}
-	// Perform benchmark
-	sandbox := client.InitLinuxSandbox(sandboxID)
-	pod, err := client.StartSandbox(sandbox, handler)
-	if err != nil {
-		return nil, err
-	}
-	container, err := client.CreateContainerWithResources(sandbox, pod, containerID, defaultSysbenchImage, args, resources[0])
+// RunInSysbenchWithScalingResources executes a sysbench benchmark in the given sandbox,
+// updating the container resources in the given interval. It returns the application logs.
+func RunInSysbenchWithScalingResources(bm benchmark.Benchmark, client runtime.Runtime, sandbox *runtime.Sandbox, args []string, resources []*runtimeapi.LinuxContainerResources, interval time.Duration) ([]byte, error) {
+	containerID := benchmark.ID(bm)
+	container, err := runtime.CreateContainerWithResources(client, sandbox.Config, sandbox.ID, containerID, defaultSysbenchImage, args, resources[0])
 	if err != nil {
 		return nil, err
 	}
@@ -74,7 +46,7 @@ func RunInSysbenchWithScalingResources(bm benchmark.Benchmark, client *runtime.C
 		return nil, err
 	}
 	for i := 1; i < len(resources); i++ {
-		state, err := client.State(container)
+		state, err := runtime.State(client, container)
 		if err != nil {
 			return nil, err
 		}
@@ -86,56 +58,86 @@ func RunInSysbenchWithScalingResources(bm benchmark.Benchmark, client *runtime.C
 		}
 		<-time.After(interval)
 	}
-	logs, err := client.WaitForLogs(container)
+	logs, err := runtime.WaitForLogs(client, container)
 	if err != nil {
+		removeAfterFailure(client, container)
 		return nil, err
 	}
-	// Cleanup container and sandbox
-	if err := client.StopAndRemoveContainer(container); err != nil {
+	// Cleanup container
+	if err := runtime.StopAndRemoveContainer(client, container); err != nil {
 		return nil, err
 	}
-	if err := client.StopAndRemoveSandbox(pod); err != nil {
+	return logs, nil
+}
+
+var cpuLimitsLabels = []string{"TotalTime", "EventsPerSecond", "AvgLatency", "P95Latency"}
+
+// cpuLimitsReport parses the logs of a CPU bound sysbench run under resource limits.
+func cpuLimitsReport(logs []byte) (benchmark.Report, error) {
+	result, err := ParseSysbench(SysbenchCPU, logs)
+	if err != nil {
 		return nil, err
 	}
-	return logs, nil
+	return benchmark.ValueReport{
+		"TotalTime":       result.TotalTime,
+		"EventsPerSecond": result.EventsPerSecond,
+		"AvgLatency":      result.Latency.Avg,
+		"P95Latency":      result.Latency.P95,
+	}, nil
 }
 
 // CPULimits measures the total time taken by a CPU heavy task.
-type CPULimits struct{}
+type CPULimits struct {
+	sysbench
+}
 
 func (CPULimits) Name() string {
 	return "limits.cpu.time"
 }
 
-func (bm *CPULimits) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	logs, err := RunInSysbenchWithResources(bm, client, handler, []string{
+func (bm *CPULimits) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *CPULimits) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	logs, err := RunInSysbenchWithOptions(bm, client, sandbox, []string{
 		"sysbench", "--test=cpu",
 		"--cpu-max-prime=20000",
 		"--num-threads=1", "run",
-	}, &runtimeapi.LinuxContainerResources{
-		CpuPeriod: 100000,
-		CpuQuota:  10000,
+	}, runtime.ContainerOptions{
+		Resources: &runtimeapi.LinuxContainerResources{
+			CpuPeriod: 100000,
+			CpuQuota:  10000,
+		},
 	})
 	if err != nil {
 		return nil, err
 	}
-	return benchmark.ValueReport{
-		"TotalTime": util.ParsePrefixedLine(logs, "total time:"),
-	}, nil
+	return cpuLimitsReport(logs)
+}
+
+func (CPULimits) Tags() []string {
+	return []string{"cpu", "limits"}
 }
 
 func (CPULimits) Labels() []string {
-	return []string{"TotalTime"}
+	return cpuLimitsLabels
 }
 
 // CPUScalingLimits measures the total time taken by a CPU heavy task.
-type CPUScalingLimits struct{}
+type CPUScalingLimits struct {
+	sysbench
+}
 
 func (CPUScalingLimits) Name() string {
 	return "limits.cpu.scaling"
 }
 
-func (bm *CPUScalingLimits) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
+func (bm *CPUScalingLimits) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *CPUScalingLimits) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
 	resources := make([]*runtimeapi.LinuxContainerResources, 10)
 	for i := 0; i < 10; i++ {
 		resources[i] = &runtimeapi.LinuxContainerResources{
@@ -143,7 +145,7 @@ func (bm *CPUScalingLimits) Run(client *runtime.Client, handler string) (benchma
 			CpuQuota:  50000 + 5000*int64(i+1),
 		}
 	}
-	logs, err := RunInSysbenchWithScalingResources(bm, client, handler, []string{
+	logs, err := RunInSysbenchWithScalingResources(bm, client, sandbox, []string{
 		"sysbench", "--test=cpu",
 		"--cpu-max-prime=20000",
 		"--num-threads=1", "run",
@@ -151,11 +153,13 @@ func (bm *CPUScalingLimits) Run(client *runtime.Client, handler string) (benchma
 	if err != nil {
 		return nil, err
 	}
-	return benchmark.ValueReport{
-		"TotalTime": util.ParsePrefixedLine(logs, "total time:"),
-	}, nil
+	return cpuLimitsReport(logs)
+}
+
+func (CPUScalingLimits) Tags() []string {
+	return []string{"cpu", "limits", "slow"}
 }
 
 func (CPUScalingLimits) Labels() []string {
-	return []string{"TotalTime"}
+	return cpuLimitsLabels
 }
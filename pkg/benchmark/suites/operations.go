@@ -7,66 +7,131 @@ import (
 	"github.com/lnsp/touchstone/pkg/runtime"
 )
 
-var Operations = []benchmark.Benchmark{
-	&ContainerLifecycle{},
+var Operations = []benchmark.Entry{
+	{
+		Benchmark: &ContainerLifecycle{},
+		Info: benchmark.Info{
+			Description: "Time taken to create, run and destroy a sandbox with a single container, split into CRI and runtime delays.",
+			Metrics: []benchmark.Metric{
+				{Label: "Create", Unit: "s", Description: "Create sandbox and container"},
+				{Label: "Run", Unit: "s", Description: "Start container"},
+				{Label: "Destroy", Unit: "s", Description: "Stop and remove container and sandbox"},
+				{Label: "CreateAndRun", Unit: "s", Description: "Create sandbox, create and start container"},
+				{Label: "SandboxCreateDelay", Unit: "s", Description: "Client request to sandbox creation reported by the runtime"},
+				{Label: "ContainerCreateDelay", Unit: "s", Description: "Client request to container creation reported by the runtime"},
+				{Label: "RuntimeCreateToStart", Unit: "s", Description: "Container creation to start reported by the runtime"},
+				{Label: "StartDelay", Unit: "s", Description: "Client start request to container start reported by the runtime"},
+				{Label: "StartToFirstLog", Unit: "s", Description: "Container start to its first log line"},
+			},
+			Estimate: 5 * time.Second,
+		},
+	},
 }
 
+const firstLogTimeout = 10 * time.Second
+
+const busyboxImage = "busybox:latest"
+
+// ContainerLifecycle measures the time taken to create, run and destroy a container.
+// Runtime-reported timestamps are combined with client timestamps to split up
+// the time spent in the CRI from the time spent in the OCI runtime.
 type ContainerLifecycle struct{}
 
 func (ContainerLifecycle) Name() string {
 	return "operations.container.lifecycle"
 }
 
-func (bm *ContainerLifecycle) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
+func (ContainerLifecycle) Images() []string {
+	return []string{busyboxImage}
+}
+
+func (bm *ContainerLifecycle) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
 	var (
 		sandboxID                    = benchmark.ID(bm)
 		containerID                  = benchmark.ID(bm)
-		image                        = "busybox:latest"
+		image                        = busyboxImage
 		beginStartup, endStartup     time.Time // measuring total time
 		beginSandbox, endSandbox     time.Time // measuring create sandbox and container
+		beginCreate                  time.Time // measuring create container
 		beginContainer, endContainer time.Time // measuring start containerr
 		beginShutdown, endShutdown   time.Time // measuring stop container & sandbox
 	)
-	// Pull image
-	if err := client.PullImage(image, nil); err != nil {
-		return nil, err
-	}
 	// Perform benchmark
-	sandbox := client.InitLinuxSandbox(sandboxID)
+	sandbox := runtime.InitLinuxSandbox(sandboxID)
 	beginStartup = time.Now()
 	beginSandbox = time.Now()
 	pod, err := client.StartSandbox(sandbox, handler)
 	if err != nil {
 		return nil, err
 	}
-	container, err := client.CreateContainer(sandbox, pod, containerID, image, []string{"sleep", "60"})
+	beginCreate = time.Now()
+	container, err := runtime.CreateContainer(client, sandbox, pod, containerID, image, []string{"sh", "-c", "echo started && sleep 60"})
 	if err != nil {
+		removeSandboxAfterFailure(client, pod)
 		return nil, err
 	}
 	endSandbox = time.Now()
 	beginContainer = time.Now()
 	if err := client.StartContainer(container); err != nil {
+		cleanupAfterFailure(client, pod, container)
 		return nil, err
 	}
 	endContainer = time.Now()
 	endStartup = time.Now()
+	if err := runtime.CheckRunning(client, container); err != nil {
+		cleanupAfterFailure(client, pod, container)
+		return nil, err
+	}
+	// Collect runtime-reported timestamps
+	firstLog, err := client.FirstLogTime(container, firstLogTimeout)
+	if err != nil {
+		cleanupAfterFailure(client, pod, container)
+		return nil, err
+	}
+	sandboxStatus, err := client.SandboxStatus(pod)
+	if err != nil {
+		cleanupAfterFailure(client, pod, container)
+		return nil, err
+	}
+	containerStatus, err := client.Status(container)
+	if err != nil {
+		cleanupAfterFailure(client, pod, container)
+		return nil, err
+	}
+	var (
+		sandboxCreated   = time.Unix(0, sandboxStatus.CreatedAt)
+		containerCreated = time.Unix(0, containerStatus.CreatedAt)
+		containerStarted = time.Unix(0, containerStatus.StartedAt)
+	)
 	beginShutdown = time.Now()
 	// Cleanup container and sandbox
-	if err := client.StopAndRemoveContainer(container); err != nil {
+	if err := runtime.StopAndRemoveContainer(client, container); err != nil {
 		return nil, err
 	}
-	if err := client.StopAndRemoveSandbox(pod); err != nil {
+	if err := runtime.StopAndRemoveSandbox(client, pod); err != nil {
 		return nil, err
 	}
 	endShutdown = time.Now()
 	return benchmark.ValueReport{
-		"CreateAndRun": endStartup.Sub(beginStartup).Seconds(),
-		"Create":       endSandbox.Sub(beginSandbox).Seconds(),
-		"Run":          endContainer.Sub(beginContainer).Seconds(),
-		"Destroy":      endShutdown.Sub(beginShutdown).Seconds(),
+		"CreateAndRun":         endStartup.Sub(beginStartup).Seconds(),
+		"Create":               endSandbox.Sub(beginSandbox).Seconds(),
+		"Run":                  endContainer.Sub(beginContainer).Seconds(),
+		"Destroy":              endShutdown.Sub(beginShutdown).Seconds(),
+		"SandboxCreateDelay":   sandboxCreated.Sub(beginSandbox).Seconds(),
+		"ContainerCreateDelay": containerCreated.Sub(beginCreate).Seconds(),
+		"RuntimeCreateToStart": containerStarted.Sub(containerCreated).Seconds(),
+		"StartDelay":           containerStarted.Sub(beginContainer).Seconds(),
+		"StartToFirstLog":      firstLog.Sub(containerStarted).Seconds(),
 	}, nil
 }
 
+func (ContainerLifecycle) Tags() []string {
+	return []string{"startup"}
+}
+
 func (ContainerLifecycle) Labels() []string {
-	return []string{"Create", "Run", "Destroy", "CreateAndRun"}
+	return []string{
+		"Create", "Run", "Destroy", "CreateAndRun",
+		"SandboxCreateDelay", "ContainerCreateDelay", "RuntimeCreateToStart", "StartDelay", "StartToFirstLog",
+	}
 }
@@ -1,70 +1,236 @@
 package suites
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"time"
+
 	"github.com/lnsp/touchstone/pkg/benchmark"
 	"github.com/lnsp/touchstone/pkg/runtime"
-	"github.com/lnsp/touchstone/pkg/util"
 	"github.com/sirupsen/logrus"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
-var Performance = []benchmark.Benchmark{
-	&MemoryTime{},
-	&MemoryMinAvgLatency{},
-	&MemoryMaxLatency{},
-	&CPUTime{},
-	&DiskRead{},
-	&DiskWrite{},
+var Performance = []benchmark.Entry{
+	{
+		Benchmark: &MemoryTime{},
+		Info: benchmark.Info{
+			Description: "Time, throughput and latency of writing 100G in 1M blocks to memory.",
+			Metrics: []benchmark.Metric{
+				metricTotalTime,
+				{Label: "Throughput", Unit: "MiB/s", Description: "Memory write throughput", HigherIsBetter: true},
+				{Label: "OperationsPerSecond", Unit: "ops/s", Description: "Block writes per second", HigherIsBetter: true},
+				metricP95Latency,
+				metricEventsStddev,
+			},
+			Estimate: 15 * time.Second,
+		},
+	},
+	{
+		Benchmark: &MemoryMinAvgLatency{},
+		Info: benchmark.Info{
+			Description: "Minimum and average latency of writing 1M blocks to memory.",
+			Metrics: []benchmark.Metric{
+				{Label: "MinLatency", Unit: "ms", Description: "Minimum block write latency"},
+				{Label: "AvgLatency", Unit: "ms", Description: "Average block write latency"},
+			},
+			Estimate: 5 * time.Second,
+		},
+	},
+	{
+		Benchmark: &MemoryMaxLatency{},
+		Info: benchmark.Info{
+			Description: "Maximum latency of writing 1M blocks to memory.",
+			Metrics: []benchmark.Metric{
+				{Label: "MaxLatency", Unit: "ms", Description: "Maximum block write latency"},
+			},
+			Estimate: 5 * time.Second,
+		},
+	},
+	{
+		Benchmark: &CPUTime{},
+		Info: benchmark.Info{
+			Description: "Time and event rate of verifying primes up to 20000 on a single thread.",
+			Metrics: []benchmark.Metric{
+				metricTotalTime,
+				metricEventsPerSecond,
+				metricAvgLatency,
+				metricP95Latency,
+				metricEventsStddev,
+			},
+			Estimate: 30 * time.Second,
+		},
+	},
+	{
+		Benchmark: &DiskRead{},
+		Info: benchmark.Info{
+			Description: "Sequential and random read throughput of 2G of prepared files.",
+			Metrics: []benchmark.Metric{
+				{Label: "SeqReadThroughput", Unit: "MiB/s", Description: "Sequential read throughput", HigherIsBetter: true},
+				{Label: "RndReadThroughput", Unit: "MiB/s", Description: "Random read throughput", HigherIsBetter: true},
+			},
+			Estimate: 30 * time.Second,
+			Launches: 2,
+		},
+	},
+	{
+		Benchmark: &DiskWrite{},
+		Info: benchmark.Info{
+			Description: "Sequential, rewrite and random write throughput of 2G of files.",
+			Metrics: []benchmark.Metric{
+				{Label: "SeqWriteThroughput", Unit: "MiB/s", Description: "Sequential write throughput", HigherIsBetter: true},
+				{Label: "SeqRewriteThroughput", Unit: "MiB/s", Description: "Sequential rewrite throughput", HigherIsBetter: true},
+				{Label: "RndWriteThroughput", Unit: "MiB/s", Description: "Random write throughput", HigherIsBetter: true},
+			},
+			Estimate: 90 * time.Second,
+			Launches: 3,
+		},
+	},
 }
 
+// Metrics shared by the sysbench based benchmarks.
+var (
+	metricTotalTime       = benchmark.Metric{Label: "TotalTime", Unit: "s", Description: "Total time reported by sysbench"}
+	metricEventsPerSecond = benchmark.Metric{Label: "EventsPerSecond", Unit: "events/s", Description: "Events processed per second", HigherIsBetter: true}
+	metricAvgLatency      = benchmark.Metric{Label: "AvgLatency", Unit: "ms", Description: "Average event latency"}
+	metricP95Latency      = benchmark.Metric{Label: "P95Latency", Unit: "ms", Description: "95th percentile event latency"}
+	metricEventsStddev    = benchmark.Metric{Label: "EventsStddev", Unit: "events", Description: "Standard deviation of events per thread"}
+)
+
 const defaultSysbenchImage = "lnsp/sysbench:latest"
 
-// RunInSysbench executes a specific sysbench benchmark and returns the application logs.
-func RunInSysbench(bm benchmark.Benchmark, client *runtime.Client, handler string, args []string) ([]byte, error) {
-	var (
-		sandboxID   = benchmark.ID(bm)
-		containerID = benchmark.ID(bm)
-	)
-	// Pull image
-	if err := client.PullImage(defaultSysbenchImage, nil); err != nil {
-		return nil, err
-	}
-	// Perform benchmark
-	sandbox := client.InitLinuxSandbox(sandboxID)
-	pod, err := client.StartSandbox(sandbox, handler)
+const (
+	fileIODir       = "/var/tmp"
+	fileIOMountPath = "/data"
+)
+
+// sysbench provides the image and a pre-warmed sandbox to benchmarks running in the sysbench image.
+type sysbench struct {
+	sandbox *runtime.Sandbox
+}
+
+func (sysbench) Images() []string {
+	return []string{defaultSysbenchImage}
+}
+
+// SetupRun starts the sandbox for the next run.
+func (f *sysbench) SetupRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	sandbox, err := runtime.RunSandbox(client, "sysbench."+runtime.NewUUID(), handler)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	container, err := client.CreateContainer(sandbox, pod, containerID, defaultSysbenchImage, args)
+	f.sandbox = sandbox
+	return nil
+}
+
+// TeardownRun removes the sandbox of the previous run.
+func (f *sysbench) TeardownRun(ctx context.Context, client runtime.Runtime, handler string) error {
+	if f.sandbox == nil {
+		return nil
+	}
+	pod := f.sandbox.ID
+	f.sandbox = nil
+	return runtime.StopAndRemoveSandbox(client, pod)
+}
+
+// runSandboxed runs the benchmark in the pre-warmed sandbox, or in a fresh one if there is none.
+func (f *sysbench) runSandboxed(bm benchmark.Sandboxed, client runtime.Runtime, handler string) (benchmark.Report, error) {
+	if f.sandbox != nil {
+		return bm.RunInSandbox(client, f.sandbox)
+	}
+	return benchmark.RunSandboxed(bm, client, handler)
+}
+
+// RunInSysbench executes a specific sysbench benchmark in the given sandbox and returns the application logs.
+// The sysbench image has to be pulled beforehand.
+func RunInSysbench(bm benchmark.Benchmark, client runtime.Runtime, sandbox *runtime.Sandbox, args []string) ([]byte, error) {
+	return RunInSysbenchWithOptions(bm, client, sandbox, args, runtime.ContainerOptions{})
+}
+
+// RunInSysbenchWithOptions executes a specific sysbench benchmark with additional container settings.
+func RunInSysbenchWithOptions(bm benchmark.Benchmark, client runtime.Runtime, sandbox *runtime.Sandbox, args []string, opts runtime.ContainerOptions) ([]byte, error) {
+	containerID := benchmark.ID(bm)
+	container, err := client.CreateContainerWithOptions(sandbox.Config, sandbox.ID, containerID, defaultSysbenchImage, args, opts)
 	if err != nil {
 		return nil, err
 	}
 	if err := client.StartContainer(container); err != nil {
 		return nil, err
 	}
-	logs, err := client.WaitForLogs(container)
+	logs, err := runtime.WaitForLogs(client, container)
 	if err != nil {
+		removeAfterFailure(client, container)
 		return nil, err
 	}
-	// Cleanup container and sandbox
-	if err := client.StopAndRemoveContainer(container); err != nil {
-		return nil, err
-	}
-	if err := client.StopAndRemoveSandbox(pod); err != nil {
+	// Cleanup container
+	if err := runtime.StopAndRemoveContainer(client, container); err != nil {
 		return nil, err
 	}
 	logrus.WithField("name", bm.Name()).Debugf("sysbench logs: %v", string(logs))
 	return logs, nil
 }
 
-// DiskWrite measures the total read/write speed.
-type DiskWrite struct{}
+// cleanup stops and removes the container and its sandbox.
+func cleanup(client runtime.Runtime, pod, container string) error {
+	if err := runtime.StopAndRemoveContainer(client, container); err != nil {
+		return err
+	}
+	return runtime.StopAndRemoveSandbox(client, pod)
+}
+
+// cleanupAfterFailure removes the leftovers of a failed workload, logging instead of returning errors.
+func cleanupAfterFailure(client runtime.Runtime, pod, container string) {
+	if err := cleanup(client, pod, container); err != nil {
+		logrus.WithError(err).WithFields(logrus.Fields{
+			"pod":       pod,
+			"container": container,
+		}).Warn("failed to cleanup after failed workload")
+	}
+}
+
+// removeAfterFailure removes the container of a failed workload, logging instead of returning errors.
+func removeAfterFailure(client runtime.Runtime, container string) {
+	if err := runtime.StopAndRemoveContainer(client, container); err != nil {
+		logrus.WithError(err).WithField("container", container).Warn("failed to cleanup after failed workload")
+	}
+}
+
+// removeSandboxAfterFailure removes the sandbox of a failed workload, logging instead of returning errors.
+func removeSandboxAfterFailure(client runtime.Runtime, pod string) {
+	if err := runtime.StopAndRemoveSandbox(client, pod); err != nil {
+		logrus.WithError(err).WithField("pod", pod).Warn("failed to cleanup after failed workload")
+	}
+}
+
+// fileIOReport parses the logs of several fileio runs and reports the selected throughput of each run.
+func fileIOReport(logs map[string][]byte, throughput func(*SysbenchFileIOStats) float64) (benchmark.Report, error) {
+	report := benchmark.ValueReport{}
+	for label, data := range logs {
+		result, err := ParseSysbench(SysbenchFileIO, data)
+		if err != nil {
+			return nil, err
+		}
+		report[label] = throughput(result.FileIO)
+	}
+	return report, nil
+}
+
+// DiskWrite measures the sequential and random write throughput in MiB/s.
+type DiskWrite struct {
+	sysbench
+}
 
 func (DiskWrite) Name() string {
 	return "performance.disk.write"
 }
 
-func (bm *DiskWrite) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	seqwr, err := RunInSysbench(bm, client, handler, []string{
+func (bm *DiskWrite) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *DiskWrite) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	seqwr, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=fileio",
 		"--file-test-mode=seqwr",
 		"--num-threads=1", "run",
@@ -72,7 +238,7 @@ func (bm *DiskWrite) Run(client *runtime.Client, handler string) (benchmark.Repo
 	if err != nil {
 		return nil, err
 	}
-	seqrewr, err := RunInSysbench(bm, client, handler, []string{
+	seqrewr, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=fileio",
 		"--file-test-mode=seqrewr",
 		"--num-threads=1", "run",
@@ -80,7 +246,7 @@ func (bm *DiskWrite) Run(client *runtime.Client, handler string) (benchmark.Repo
 	if err != nil {
 		return nil, err
 	}
-	rndwr, err := RunInSysbench(bm, client, handler, []string{
+	rndwr, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=fileio",
 		"--file-test-mode=rndwr",
 		"--num-threads=1", "run",
@@ -88,56 +254,136 @@ func (bm *DiskWrite) Run(client *runtime.Client, handler string) (benchmark.Repo
 	if err != nil {
 		return nil, err
 	}
-	return benchmark.ValueReport{
-		"SeqWrite":   util.ParsePrefixedLine(seqwr, "total time:"),
-		"SeqRewrite": util.ParsePrefixedLine(seqrewr, "total time:"),
-		"RndWrite":   util.ParsePrefixedLine(rndwr, "total time:"),
-	}, nil
+	return fileIOReport(map[string][]byte{
+		"SeqWriteThroughput":   seqwr,
+		"SeqRewriteThroughput": seqrewr,
+		"RndWriteThroughput":   rndwr,
+	}, func(result *SysbenchFileIOStats) float64 {
+		return result.WrittenMiBPerSecond
+	})
+}
+
+func (DiskWrite) Tags() []string {
+	return []string{"io", "slow"}
 }
 
 func (DiskWrite) Labels() []string {
-	return []string{"SeqWrite", "SeqRewrite", "RndWrite"}
+	return []string{"SeqWriteThroughput", "SeqRewriteThroughput", "RndWriteThroughput"}
 }
 
-// DiskRead measures the total read/write speed.
-type DiskRead struct{}
+// DiskRead measures the sequential and random read throughput in MiB/s.
+// The test files are prepared once per matrix entry and shared by all runs.
+type DiskRead struct {
+	sysbench
+	dir string
+}
 
 func (DiskRead) Name() string {
 	return "performance.disk.read"
 }
 
-func (bm *DiskRead) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	seqrd, err := RunInSysbench(bm, client, handler, []string{
-		"sh", "-c", "sysbench --test=fileio prepare && sysbench --test=fileio --file-test-mode=seqrd --num-threads=1 run",
-	})
+// Setup prepares the sysbench test files in a host directory.
+// The directory is removed again if the files can not be prepared.
+func (bm *DiskRead) Setup(ctx context.Context, client runtime.Runtime, handler string) (err error) {
+	dir, err := ioutil.TempDir(fileIODir, "touchstone-fileio")
+	if err != nil {
+		return err
+	}
+	bm.dir = dir
+	defer func() {
+		if err == nil {
+			return
+		}
+		bm.dir = ""
+		if rmErr := os.RemoveAll(dir); rmErr != nil {
+			logrus.WithError(rmErr).WithField("dir", dir).Warn("failed to remove test files")
+		}
+	}()
+	sandbox, err := runtime.RunSandbox(client, benchmark.ID(bm), handler)
+	if err != nil {
+		return err
+	}
+	_, err = RunInSysbenchWithOptions(bm, client, sandbox, []string{
+		"sysbench", "--test=fileio", "prepare",
+	}, bm.options())
+	if rmErr := runtime.StopAndRemoveSandbox(client, sandbox.ID); rmErr != nil && err == nil {
+		err = rmErr
+	}
+	return err
+}
+
+// Teardown removes the prepared test files.
+func (bm *DiskRead) Teardown(ctx context.Context, client runtime.Runtime, handler string) error {
+	if bm.dir == "" {
+		return nil
+	}
+	dir := bm.dir
+	bm.dir = ""
+	return os.RemoveAll(dir)
+}
+
+// options mounts the prepared test files into the working directory of the container.
+func (bm *DiskRead) options() runtime.ContainerOptions {
+	return runtime.ContainerOptions{
+		Mounts: []*runtimeapi.Mount{
+			{ContainerPath: fileIOMountPath, HostPath: bm.dir},
+		},
+		WorkingDir: fileIOMountPath,
+	}
+}
+
+func (bm *DiskRead) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *DiskRead) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	seqrd, err := RunInSysbenchWithOptions(bm, client, sandbox, []string{
+		"sysbench", "--test=fileio",
+		"--file-test-mode=seqrd",
+		"--num-threads=1", "run",
+	}, bm.options())
 	if err != nil {
 		return nil, err
 	}
-	rndrd, err := RunInSysbench(bm, client, handler, []string{
-		"sh", "-c", "sysbench --test=fileio prepare && sysbench --test=fileio --file-test-mode=rndrd --num-threads=1 run",
-	})
+	rndrd, err := RunInSysbenchWithOptions(bm, client, sandbox, []string{
+		"sysbench", "--test=fileio",
+		"--file-test-mode=rndrd",
+		"--num-threads=1", "run",
+	}, bm.options())
 	if err != nil {
 		return nil, err
 	}
-	return benchmark.ValueReport{
-		"SeqRead": util.ParsePrefixedLine(seqrd, "total time:"),
-		"RndRead": util.ParsePrefixedLine(rndrd, "total time:"),
-	}, nil
+	return fileIOReport(map[string][]byte{
+		"SeqReadThroughput": seqrd,
+		"RndReadThroughput": rndrd,
+	}, func(result *SysbenchFileIOStats) float64 {
+		return result.ReadMiBPerSecond
+	})
+}
+
+func (DiskRead) Tags() []string {
+	return []string{"io", "slow"}
 }
 
 func (DiskRead) Labels() []string {
-	return []string{"SeqRead", "RndRead"}
+	return []string{"SeqReadThroughput", "RndReadThroughput"}
 }
 
-// CPUTime measures the total time taken by a CPU heavy task.
-type CPUTime struct{}
+// CPUTime measures the total time and event rate of a CPU heavy task.
+type CPUTime struct {
+	sysbench
+}
 
 func (CPUTime) Name() string {
 	return "performance.cpu.time"
 }
 
-func (bm *CPUTime) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	logs, err := RunInSysbench(bm, client, handler, []string{
+func (bm *CPUTime) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *CPUTime) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	logs, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=cpu",
 		"--cpu-max-prime=20000",
 		"--num-threads=1", "run",
@@ -145,24 +391,42 @@ func (bm *CPUTime) Run(client *runtime.Client, handler string) (benchmark.Report
 	if err != nil {
 		return nil, err
 	}
+	result, err := ParseSysbench(SysbenchCPU, logs)
+	if err != nil {
+		return nil, err
+	}
 	return benchmark.ValueReport{
-		"TotalTime": util.ParsePrefixedLine(logs, "total time:"),
+		"TotalTime":       result.TotalTime,
+		"EventsPerSecond": result.EventsPerSecond,
+		"AvgLatency":      result.Latency.Avg,
+		"P95Latency":      result.Latency.P95,
+		"EventsStddev":    result.Fairness.EventsStddev,
 	}, nil
 }
 
+func (CPUTime) Tags() []string {
+	return []string{"cpu"}
+}
+
 func (CPUTime) Labels() []string {
-	return []string{"TotalTime"}
+	return []string{"TotalTime", "EventsPerSecond", "AvgLatency", "P95Latency", "EventsStddev"}
 }
 
-// MemoryTime measures the total memory operation time.
-type MemoryTime struct{}
+// MemoryTime measures the total memory operation time and throughput.
+type MemoryTime struct {
+	sysbench
+}
 
 func (MemoryTime) Name() string {
 	return "performance.memory.total"
 }
 
-func (bm *MemoryTime) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	logs, err := RunInSysbench(bm, client, handler, []string{
+func (bm *MemoryTime) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *MemoryTime) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	logs, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=memory",
 		"--memory-block-size=1M", "--memory-total-size=100G",
 		"--num-threads=1", "run",
@@ -170,24 +434,42 @@ func (bm *MemoryTime) Run(client *runtime.Client, handler string) (benchmark.Rep
 	if err != nil {
 		return nil, err
 	}
+	result, err := ParseSysbench(SysbenchMemory, logs)
+	if err != nil {
+		return nil, err
+	}
 	return benchmark.ValueReport{
-		"TotalTime": util.ParsePrefixedLine(logs, "total time:"),
+		"TotalTime":           result.TotalTime,
+		"Throughput":          result.Memory.MiBPerSecond,
+		"OperationsPerSecond": result.Memory.OperationsPerSecond,
+		"P95Latency":          result.Latency.P95,
+		"EventsStddev":        result.Fairness.EventsStddev,
 	}, nil
 }
 
+func (MemoryTime) Tags() []string {
+	return []string{"memory"}
+}
+
 func (MemoryTime) Labels() []string {
-	return []string{"TotalTime"}
+	return []string{"TotalTime", "Throughput", "OperationsPerSecond", "P95Latency", "EventsStddev"}
 }
 
 // MemoryMinAvgLatency measures the total memory operations and min/avg/max memory latency.
-type MemoryMinAvgLatency struct{}
+type MemoryMinAvgLatency struct {
+	sysbench
+}
 
 func (MemoryMinAvgLatency) Name() string {
 	return "performance.memory.minavglatency"
 }
 
-func (bm *MemoryMinAvgLatency) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	logs, err := RunInSysbench(bm, client, handler, []string{
+func (bm *MemoryMinAvgLatency) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *MemoryMinAvgLatency) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	logs, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=memory",
 		"--memory-block-size=1M", "--memory-total-size=1G",
 		"--num-threads=1", "run",
@@ -195,25 +477,39 @@ func (bm *MemoryMinAvgLatency) Run(client *runtime.Client, handler string) (benc
 	if err != nil {
 		return nil, err
 	}
+	result, err := ParseSysbench(SysbenchMemory, logs)
+	if err != nil {
+		return nil, err
+	}
 	return benchmark.ValueReport{
-		"MinLatency": util.ParsePrefixedLine(logs, "min:"),
-		"AvgLatency": util.ParsePrefixedLine(logs, "avg:"),
+		"MinLatency": result.Latency.Min,
+		"AvgLatency": result.Latency.Avg,
 	}, nil
 }
 
+func (MemoryMinAvgLatency) Tags() []string {
+	return []string{"memory"}
+}
+
 func (MemoryMinAvgLatency) Labels() []string {
 	return []string{"MinLatency", "AvgLatency"}
 }
 
 // MemoryMaxLatency measures the total memory operation time.
-type MemoryMaxLatency struct{}
+type MemoryMaxLatency struct {
+	sysbench
+}
 
 func (MemoryMaxLatency) Name() string {
 	return "performance.memory.maxlatency"
 }
 
-func (bm *MemoryMaxLatency) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
-	logs, err := RunInSysbench(bm, client, handler, []string{
+func (bm *MemoryMaxLatency) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
+	return bm.runSandboxed(bm, client, handler)
+}
+
+func (bm *MemoryMaxLatency) RunInSandbox(client runtime.Runtime, sandbox *runtime.Sandbox) (benchmark.Report, error) {
+	logs, err := RunInSysbench(bm, client, sandbox, []string{
 		"sysbench", "--test=memory",
 		"--memory-block-size=1M", "--memory-total-size=1G",
 		"--num-threads=1", "run",
@@ -221,11 +517,19 @@ func (bm *MemoryMaxLatency) Run(client *runtime.Client, handler string) (benchma
 	if err != nil {
 		return nil, err
 	}
+	result, err := ParseSysbench(SysbenchMemory, logs)
+	if err != nil {
+		return nil, err
+	}
 	return benchmark.ValueReport{
-		"MaxLatency": util.ParsePrefixedLine(logs, "max:"),
+		"MaxLatency": result.Latency.Max,
 	}, nil
 }
 
+func (MemoryMaxLatency) Tags() []string {
+	return []string{"memory"}
+}
+
 func (MemoryMaxLatency) Labels() []string {
 	return []string{"MaxLatency"}
 }
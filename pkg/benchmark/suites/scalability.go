@@ -2,16 +2,31 @@ package suites
 
 import (
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
 	"github.com/lnsp/touchstone/pkg/runtime"
 )
 
-var Scalability = []benchmark.Benchmark{
-	&StartupScalability{Scale: 5},
-	&StartupScalability{Scale: 10},
-	&StartupScalability{Scale: 50},
+var Scalability = []benchmark.Entry{
+	scalabilityEntry(5),
+	scalabilityEntry(10),
+	scalabilityEntry(50),
+}
+
+func scalabilityEntry(scale int) benchmark.Entry {
+	return benchmark.Entry{
+		Benchmark: &StartupScalability{Scale: scale},
+		Info: benchmark.Info{
+			Description: fmt.Sprintf("Time taken to start %d sandboxes with a single container each.", scale),
+			Metrics: []benchmark.Metric{
+				{Label: "TotalTime", Unit: "s", Description: "Start all sandboxes and containers"},
+			},
+			Estimate: time.Duration(scale) * 2 * time.Second,
+			Launches: scale,
+		},
+	}
 }
 
 type StartupScalability struct {
@@ -22,44 +37,72 @@ func (bm *StartupScalability) Name() string {
 	return fmt.Sprintf("scalability.runtime.%d", bm.Scale)
 }
 
-func (bm *StartupScalability) Run(client *runtime.Client, handler string) (benchmark.Report, error) {
+// Params returns the number of sandboxes started.
+func (bm *StartupScalability) Params() map[string]string {
+	return map[string]string{"scale": strconv.Itoa(bm.Scale)}
+}
+
+// WithParams creates the benchmark for another number of sandboxes.
+func (bm *StartupScalability) WithParams(params map[string]string) (benchmark.Benchmark, error) {
+	scale, err := strconv.Atoi(params["scale"])
+	if err != nil || scale < 1 {
+		return nil, fmt.Errorf("scale must be a positive number, got %q", params["scale"])
+	}
+	return &StartupScalability{Scale: scale}, nil
+}
+
+func (StartupScalability) Images() []string {
+	return []string{busyboxImage}
+}
+
+func (bm *StartupScalability) Run(client runtime.Runtime, handler string) (benchmark.Report, error) {
 	var (
 		sandboxNames   = make([]string, bm.Scale)
 		containerNames = make([]string, bm.Scale)
 		podIDs         = make([]string, bm.Scale)
 		containerIDs   = make([]string, bm.Scale)
-		image          = "busybox:latest"
+		image          = busyboxImage
 	)
-	err := client.PullImage(image, nil)
-	if err != nil {
-		return nil, err
-	}
+	var err error
 	for i := 0; i < bm.Scale; i++ {
 		containerNames[i] = benchmark.ID(bm)
 		sandboxNames[i] = benchmark.ID(bm)
 	}
+	// cleanupFirst removes the first n sandboxes and their containers
+	cleanupFirst := func(n int) {
+		for j := 0; j < n; j++ {
+			cleanupAfterFailure(client, podIDs[j], containerIDs[j])
+		}
+	}
 	start := time.Now()
 	for i := 0; i < bm.Scale; i++ {
-		sandbox := client.InitLinuxSandbox(sandboxNames[i])
+		sandbox := runtime.InitLinuxSandbox(sandboxNames[i])
 		podIDs[i], err = client.StartSandbox(sandbox, handler)
 		if err != nil {
+			cleanupFirst(i)
 			return nil, err
 		}
-		containerIDs[i], err = client.CreateContainer(sandbox, podIDs[i], containerNames[i], image, []string{"sleep", "1000000"})
+		containerIDs[i], err = runtime.CreateContainer(client, sandbox, podIDs[i], containerNames[i], image, []string{"sleep", "1000000"})
 		if err != nil {
+			removeSandboxAfterFailure(client, podIDs[i])
+			cleanupFirst(i)
 			return nil, err
 		}
 		if err := client.StartContainer(containerIDs[i]); err != nil {
+			cleanupFirst(i + 1)
 			return nil, err
 		}
 	}
 	end := time.Now()
-	// cleanup
 	for i := 0; i < bm.Scale; i++ {
-		if err := client.StopAndRemoveContainer(containerIDs[i]); err != nil {
+		if err := runtime.CheckRunning(client, containerIDs[i]); err != nil {
+			cleanupFirst(bm.Scale)
 			return nil, err
 		}
-		if err := client.StopAndRemoveSandbox(podIDs[i]); err != nil {
+	}
+	// cleanup
+	for i := 0; i < bm.Scale; i++ {
+		if err := cleanup(client, podIDs[i], containerIDs[i]); err != nil {
 			return nil, err
 		}
 	}
@@ -68,6 +111,13 @@ func (bm *StartupScalability) Run(client *runtime.Client, handler string) (bench
 	}, nil
 }
 
+func (bm *StartupScalability) Tags() []string {
+	if bm.Scale > 10 {
+		return []string{"startup", "scalability", "slow"}
+	}
+	return []string{"startup", "scalability"}
+}
+
 func (StartupScalability) Labels() []string {
 	return []string{"TotalTime"}
 }
@@ -0,0 +1,188 @@
+package suites
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/lnsp/touchstone/pkg/parser"
+)
+
+// Sysbench test modes understood by ParseSysbench.
+const (
+	SysbenchCPU     = "cpu"
+	SysbenchMemory  = "memory"
+	SysbenchFileIO  = "fileio"
+	SysbenchThreads = "threads"
+)
+
+// SysbenchLatency holds the per-request latency statistics in milliseconds.
+type SysbenchLatency struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+	P95 float64 `json:"p95"`
+}
+
+// SysbenchFairness holds the per-thread fairness statistics.
+type SysbenchFairness struct {
+	EventsAvg    float64 `json:"eventsAvg"`
+	EventsStddev float64 `json:"eventsStddev"`
+	TimeAvg      float64 `json:"timeAvg"`
+	TimeStddev   float64 `json:"timeStddev"`
+}
+
+// SysbenchMemoryStats holds the results specific to the memory test.
+type SysbenchMemoryStats struct {
+	OperationsPerSecond float64 `json:"operationsPerSecond"`
+	TransferredMiB      float64 `json:"transferredMiB"`
+	MiBPerSecond        float64 `json:"mibPerSecond"`
+}
+
+// SysbenchFileIOStats holds the results specific to the fileio test.
+type SysbenchFileIOStats struct {
+	ReadsPerSecond      float64 `json:"readsPerSecond"`
+	WritesPerSecond     float64 `json:"writesPerSecond"`
+	ReadMiBPerSecond    float64 `json:"readMiBPerSecond"`
+	WrittenMiBPerSecond float64 `json:"writtenMiBPerSecond"`
+}
+
+// SysbenchResult is the parsed report of a single sysbench run.
+// Both the 0.4 and the 1.0 output formats are supported.
+type SysbenchResult struct {
+	Test            string               `json:"test"`
+	TotalTime       float64              `json:"totalTime"`
+	Events          float64              `json:"events"`
+	EventsPerSecond float64              `json:"eventsPerSecond"`
+	Latency         SysbenchLatency      `json:"latency"`
+	Fairness        SysbenchFairness     `json:"fairness"`
+	Memory          *SysbenchMemoryStats `json:"memory,omitempty"`
+	FileIO          *SysbenchFileIOStats `json:"fileio,omitempty"`
+}
+
+// ParseSysbench parses the output of a sysbench run of the given test mode.
+func ParseSysbench(test string, data []byte) (*SysbenchResult, error) {
+	values, err := parser.Extract(data, parser.Fields{
+		"TotalTime":      parser.Sysbench(parser.SysbenchTotalTime),
+		"Events":         parser.Sysbench(parser.SysbenchEvents),
+		"LatencyMin":     parser.Sysbench(parser.SysbenchLatencyMin),
+		"LatencyAvg":     parser.Sysbench(parser.SysbenchLatencyAvg),
+		"LatencyMax":     parser.Sysbench(parser.SysbenchLatencyMax),
+		"LatencyP95":     parser.Sysbench(parser.SysbenchLatency95),
+		"EventsAvg":      parser.Regexp(`events \(avg/stddev\):\s*([\d.]+)/`),
+		"EventsStddev":   parser.Regexp(`events \(avg/stddev\):\s*[\d.]+/([\d.]+)`),
+		"ExecTimeAvg":    parser.Regexp(`execution time \(avg/stddev\):\s*([\d.]+)/`),
+		"ExecTimeStddev": parser.Regexp(`execution time \(avg/stddev\):\s*[\d.]+/([\d.]+)`),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := &SysbenchResult{
+		Test:      test,
+		TotalTime: values["TotalTime"],
+		Events:    values["Events"],
+		Latency: SysbenchLatency{
+			Min: values["LatencyMin"],
+			Avg: values["LatencyAvg"],
+			Max: values["LatencyMax"],
+			P95: values["LatencyP95"],
+		},
+		Fairness: SysbenchFairness{
+			EventsAvg:    values["EventsAvg"],
+			EventsStddev: values["EventsStddev"],
+			TimeAvg:      values["ExecTimeAvg"],
+			TimeStddev:   values["ExecTimeStddev"],
+		},
+	}
+	// sysbench 0.4 does not report the event rate, so derive it from the total time
+	if eps, err := parser.Prefix("events per second:").Extract(data); err == nil {
+		result.EventsPerSecond = eps
+	} else if result.TotalTime > 0 {
+		result.EventsPerSecond = result.Events / result.TotalTime
+	}
+	switch test {
+	case SysbenchMemory:
+		result.Memory, err = parseSysbenchMemory(data)
+	case SysbenchFileIO:
+		result.FileIO, err = parseSysbenchFileIO(data, result.TotalTime)
+	case SysbenchCPU, SysbenchThreads:
+	default:
+		return nil, fmt.Errorf("unknown sysbench test %q", test)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func parseSysbenchMemory(data []byte) (*SysbenchMemoryStats, error) {
+	values, err := parser.Extract(data, parser.Fields{
+		"OperationsPerSecond": parser.Regexp(`(?:Operations performed|Total operations):\s*\d+\s*\(\s*([\d.]+)`),
+		"TransferredMiB":      parser.Regexp(`([\d.]+) Mi?B transferred`),
+		"MiBPerSecond":        parser.Regexp(`transferred \(([\d.]+) Mi?B/sec\)`),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SysbenchMemoryStats{
+		OperationsPerSecond: values["OperationsPerSecond"],
+		TransferredMiB:      values["TransferredMiB"],
+		MiBPerSecond:        values["MiBPerSecond"],
+	}, nil
+}
+
+var (
+	legacyFileIOOperations = regexp.MustCompile(`Operations performed:\s*(\d+) Reads?, (\d+) Writes?`)
+	legacyFileIOTransfer   = regexp.MustCompile(`Read ([\d.]+)([KMGT]?b)\s+Written ([\d.]+)([KMGT]?b)\s+Total transferred [\d.]+[KMGT]?b\s+\(([\d.]+)([KMGT]?b)/sec\)`)
+)
+
+var legacySizeUnits = map[string]float64{
+	"b":  1,
+	"Kb": 1 << 10,
+	"Mb": 1 << 20,
+	"Gb": 1 << 30,
+	"Tb": 1 << 40,
+}
+
+func parseSysbenchFileIO(data []byte, totalTime float64) (*SysbenchFileIOStats, error) {
+	values, err := parser.Extract(data, parser.Fields{
+		"ReadsPerSecond":      parser.Prefix("reads/s:"),
+		"WritesPerSecond":     parser.Prefix("writes/s:"),
+		"ReadMiBPerSecond":    parser.Prefix("read, MiB/s:"),
+		"WrittenMiBPerSecond": parser.Prefix("written, MiB/s:"),
+	})
+	if err == nil {
+		return &SysbenchFileIOStats{
+			ReadsPerSecond:      values["ReadsPerSecond"],
+			WritesPerSecond:     values["WritesPerSecond"],
+			ReadMiBPerSecond:    values["ReadMiBPerSecond"],
+			WrittenMiBPerSecond: values["WrittenMiBPerSecond"],
+		}, nil
+	}
+	// sysbench 0.4 only reports totals, so derive the rates from them
+	ops := legacyFileIOOperations.FindSubmatch(data)
+	transfer := legacyFileIOTransfer.FindSubmatch(data)
+	if ops == nil || transfer == nil || totalTime <= 0 {
+		return nil, err
+	}
+	var numbers [5]float64
+	for i, match := range [][]byte{ops[1], ops[2], transfer[1], transfer[3], transfer[5]} {
+		if numbers[i], err = strconv.ParseFloat(string(match), 64); err != nil {
+			return nil, err
+		}
+	}
+	reads, writes := numbers[0], numbers[1]
+	read := numbers[2] * legacySizeUnits[string(transfer[2])]
+	written := numbers[3] * legacySizeUnits[string(transfer[4])]
+	// the rate is given in the unit of its own suffix, report it in MiB/s
+	rate := numbers[4] * legacySizeUnits[string(transfer[6])] / legacySizeUnits["Mb"]
+	result := &SysbenchFileIOStats{
+		ReadsPerSecond:  reads / totalTime,
+		WritesPerSecond: writes / totalTime,
+	}
+	if total := read + written; total > 0 {
+		result.ReadMiBPerSecond = rate * read / total
+		result.WrittenMiBPerSecond = rate * written / total
+	}
+	return result, nil
+}
@@ -0,0 +1,183 @@
+package suites
+
+import (
+	"testing"
+)
+
+func TestParseSysbench(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Test   string
+		Output []byte
+		Check  func(*SysbenchResult) bool
+	}{
+		{
+			Name: "cpu-0.4",
+			Test: SysbenchCPU,
+			Output: []byte(`sysbench 0.4.12:  multi-threaded system evaluation benchmark
+
+Test execution summary:
+    total time:                          10.0000s
+    total number of events:              10000
+    total time taken by event execution: 10.0610
+    per-request statistics:
+         min:                                  0.86ms
+         avg:                                  1.01ms
+         max:                                  2.91ms
+         approx.  95 percentile:               1.33ms
+
+Threads fairness:
+    events (avg/stddev):           10000.0000/0.00
+    execution time (avg/stddev):   10.0610/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.TotalTime == 10 && r.EventsPerSecond == 1000 && r.Latency.P95 == 1.33 && r.Fairness.TimeAvg == 10.061
+			},
+		},
+		{
+			Name: "cpu-1.0",
+			Test: SysbenchCPU,
+			Output: []byte(`sysbench 1.0.18 (using system LuaJIT 2.1.0-beta3)
+
+CPU speed:
+    events per second:  1094.37
+
+General statistics:
+    total time:                          10.0007s
+    total number of events:              10945
+
+Latency (ms):
+         min:                                    0.88
+         avg:                                    0.91
+         max:                                    2.12
+         95th percentile:                        0.97
+         sum:                                 9996.05
+
+Threads fairness:
+    events (avg/stddev):           10945.0000/0.00
+    execution time (avg/stddev):   9.9960/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.EventsPerSecond == 1094.37 && r.Latency.Max == 2.12 && r.Latency.P95 == 0.97
+			},
+		},
+		{
+			Name: "memory-0.4",
+			Test: SysbenchMemory,
+			Output: []byte(`Operations performed: 102400 (10221.21 ops/sec)
+
+102400.00 MB transferred (10221.21 MB/sec)
+
+
+Test execution summary:
+	total time:                          10.0184s
+	total number of events:              102400
+	total time taken by event execution: 10.0103
+	per-request statistics:
+		 min:                                  0.09ms
+		 avg:                                  0.10ms
+		 max:                                  1.53ms
+		 approx.  95 percentile:               0.13ms
+
+Threads fairness:
+	events (avg/stddev):           102400.0000/0.00
+	execution time (avg/stddev):   10.0103/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.Memory.MiBPerSecond == 10221.21 && r.Memory.TransferredMiB == 102400 && r.Memory.OperationsPerSecond == 10221.21
+			},
+		},
+		{
+			Name: "fileio-0.4",
+			Test: SysbenchFileIO,
+			Output: []byte(`Operations performed:  0 Read, 131072 Write, 128 Other = 131200 Total
+Read 0b  Written 2Gb  Total transferred 2Gb  (83.139Mb/sec)
+ 5320.90 Requests/sec executed
+
+Test execution summary:
+    total time:                          24.6334s
+    total number of events:              131072
+    total time taken by event execution: 1.8725
+    per-request statistics:
+         min:                                  0.01ms
+         avg:                                  0.01ms
+         max:                                 20.28ms
+         approx.  95 percentile:               0.02ms
+
+Threads fairness:
+    events (avg/stddev):           131072.0000/0.00
+    execution time (avg/stddev):   1.8725/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.FileIO.WrittenMiBPerSecond == 83.139 && r.FileIO.ReadMiBPerSecond == 0 && r.FileIO.WritesPerSecond > 5320
+			},
+		},
+		{
+			Name: "fileio-0.4-kb",
+			Test: SysbenchFileIO,
+			Output: []byte(`Operations performed:  6400 Read, 0 Write, 0 Other = 6400 Total
+Read 100Mb  Written 0b  Total transferred 100Mb  (512Kb/sec)
+ 40.00 Requests/sec executed
+
+Test execution summary:
+    total time:                          160.0000s
+    total number of events:              6400
+    total time taken by event execution: 159.8000
+    per-request statistics:
+         min:                                  0.01ms
+         avg:                                 24.97ms
+         max:                                 80.12ms
+         approx.  95 percentile:              50.02ms
+
+Threads fairness:
+    events (avg/stddev):           6400.0000/0.00
+    execution time (avg/stddev):   159.8000/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.FileIO.ReadMiBPerSecond == 0.5 && r.FileIO.WrittenMiBPerSecond == 0 && r.FileIO.ReadsPerSecond == 40
+			},
+		},
+		{
+			Name: "fileio-1.0",
+			Test: SysbenchFileIO,
+			Output: []byte(`File operations:
+    reads/s:                      2795.44
+    writes/s:                     0.00
+    fsyncs/s:                     0.00
+
+Throughput:
+    read, MiB/s:                  43.68
+    written, MiB/s:               0.00
+
+General statistics:
+    total time:                          10.0003s
+    total number of events:              27960
+
+Latency (ms):
+         min:                                    0.00
+         avg:                                    0.36
+         max:                                   14.20
+         95th percentile:                        1.25
+         sum:                                 9980.81
+
+Threads fairness:
+    events (avg/stddev):           27960.0000/0.00
+    execution time (avg/stddev):   9.9808/0.00
+`),
+			Check: func(r *SysbenchResult) bool {
+				return r.FileIO.ReadMiBPerSecond == 43.68 && r.FileIO.ReadsPerSecond == 2795.44
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := ParseSysbench(tc.Test, tc.Output)
+			if err != nil {
+				t.Fatalf("could not parse output: %v", err)
+			}
+			if !tc.Check(result) {
+				t.Errorf("unexpected result %+v", result)
+			}
+		})
+	}
+}
@@ -1,55 +1,133 @@
 package config
 
 import (
-	"errors"
-	"io"
-	"io/ioutil"
-	"os"
+	"fmt"
+	"time"
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
 	"github.com/lnsp/touchstone/pkg/benchmark/suites"
-	"github.com/lnsp/touchstone/pkg/util"
-	"gopkg.in/yaml.v2"
 )
 
 type Config struct {
-	OCIs   []string `yaml:"oci"`
-	CRIs   []string `yaml:"cri"`
-	Output string   `yaml:"output"`
-	Filter []string `yaml:"filter"`
-	Runs   int      `yaml:"runs"`
-	Scale  int      `yaml:"scale"`
+	// Include lists files with defaults for the unset fields, relative to the config file.
+	Include []string `yaml:"include,omitempty"`
+	OCIs    []string `yaml:"oci,omitempty"`
+	CRIs    []string `yaml:"cri,omitempty"`
+	Output  string   `yaml:"output,omitempty"`
+	Filter  []string `yaml:"filter,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+	Runs    int      `yaml:"runs,omitempty"`
+	// Warmup is the number of unmeasured runs before the runs of each benchmark.
+	Warmup int `yaml:"warmup,omitempty"`
+	// Quiescence enables pre-run checks for a quiet host.
+	Quiescence *Quiescence `yaml:"quiescence,omitempty"`
+	// Rules restrict and adjust the combinations of CRIs and OCI handlers.
+	Rules MatrixRules `yaml:"matrix,omitempty"`
+	// Source names the file and, for files with several documents, the document of the config.
+	Source string `yaml:"-"`
+}
+
+// MatrixRules exclude and include combinations of CRIs and OCI handlers and override their settings.
+type MatrixRules struct {
+	Include   []benchmark.Combination `yaml:"include,omitempty"`
+	Exclude   []benchmark.Combination `yaml:"exclude,omitempty"`
+	Overrides []benchmark.Override    `yaml:"overrides,omitempty"`
+}
+
+// Selection returns the selection of benchmarks by the filter and exclude expressions.
+func (c *Config) Selection() benchmark.Selection {
+	return benchmark.Selection{Filter: c.Filter, Exclude: c.Exclude}
+}
+
+// Quiescence configures the host noise guards, see benchmark.Quiescence.
+type Quiescence struct {
+	MaxLoad    float64       `yaml:"maxLoad,omitempty"`
+	MaxCPU     float64       `yaml:"maxCPU,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty"`
+	Interval   time.Duration `yaml:"interval,omitempty"`
+	DropCaches bool          `yaml:"dropCaches,omitempty"`
 }
 
 func (c *Config) Matrix() (*benchmark.Matrix, error) {
-	b := benchmark.Filter(suites.All(), c.Filter)
+	b, err := c.Selection().Select(suites.All())
+	if err != nil {
+		return nil, err
+	}
 	m := &benchmark.Matrix{
-		OCIs:  c.OCIs,
-		CRIs:  c.CRIs,
-		Items: b,
-		Runs:  c.Runs,
+		OCIs:      c.OCIs,
+		CRIs:      c.CRIs,
+		Items:     b,
+		Runs:      c.Runs,
+		Warmup:    c.Warmup,
+		Registry:  suites.Registry,
+		Selection: c.Selection(),
+		Include:   c.Rules.Include,
+		Exclude:   c.Rules.Exclude,
+		Overrides: c.Rules.Overrides,
+	}
+	for _, combination := range m.Combinations() {
+		if _, _, err := m.Settings(combination); err != nil {
+			return nil, err
+		}
+	}
+	if q := c.Quiescence; q != nil {
+		m.Quiescence = &benchmark.Quiescence{
+			MaxLoad:    q.MaxLoad,
+			MaxCPU:     q.MaxCPU,
+			Timeout:    q.Timeout,
+			Interval:   q.Interval,
+			DropCaches: q.DropCaches,
+		}
 	}
 	return m, nil
 }
 
-func (c *Config) MapOutput(dir string) (io.WriteCloser, error) {
-	if c.Output == "" {
-		return os.Stdout, nil
+// Parse reads and validates a config file with a single document, see Load.
+func Parse(file string, vars Vars) (*Config, error) {
+	configs, err := Load(file, vars)
+	if err != nil {
+		return nil, err
+	}
+	if len(configs) != 1 {
+		return nil, &Error{File: file, Msg: fmt.Sprintf("expected a single config, found %d documents", len(configs))}
 	}
-	return util.GetOutputTarget(dir + c.Output), nil
+	return configs[0], nil
 }
 
-func Parse(file string) (*Config, error) {
-	data, err := ioutil.ReadFile(file)
-	if err != nil {
-		return nil, err
+// Overrides replace fields of loaded configs, like the command line flags do. Nil fields keep the values of the config.
+// A filter also drops the excludes of the config.
+type Overrides struct {
+	CRIs    []string
+	OCIs    []string
+	Filter  []string
+	Exclude []string
+	Runs    *int
+	Warmup  *int
+	Output  *string
+}
+
+// Apply replaces the fields of the config and validates the result.
+func (o *Overrides) Apply(c *Config) error {
+	if o.CRIs != nil {
+		c.CRIs = o.CRIs
 	}
-	config := &Config{}
-	if err := yaml.Unmarshal(data, config); err != nil {
-		return nil, err
+	if o.OCIs != nil {
+		c.OCIs = o.OCIs
+	}
+	if o.Filter != nil {
+		c.Filter, c.Exclude = o.Filter, nil
+	}
+	if o.Exclude != nil {
+		c.Exclude = o.Exclude
+	}
+	if o.Runs != nil {
+		c.Runs = *o.Runs
+	}
+	if o.Warmup != nil {
+		c.Warmup = *o.Warmup
 	}
-	if config.Runs < 1 {
-		return nil, errors.New("runs must be larger than 0")
+	if o.Output != nil {
+		c.Output = *o.Output
 	}
-	return config, nil
+	return c.Validate(c.Source, nil)
 }
@@ -1,10 +1,16 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/benchmark/suites"
 )
 
 func TestConfig(t *testing.T) {
@@ -22,7 +28,6 @@ cri: ["containerd", "crio"]
 filter:
 - performance
 runs: 10
-scale: 1
 `),
 			Config: &Config{
 				Output: "performance.yaml",
@@ -30,7 +35,72 @@ scale: 1
 				CRIs:   []string{"containerd", "crio"},
 				Filter: []string{"performance"},
 				Runs:   10,
-				Scale:  1,
+			},
+		},
+		{
+			Name: "quiescence",
+			Content: []byte(`
+oci: ["runc"]
+cri: ["containerd"]
+runs: 5
+quiescence:
+  maxLoad: 0.5
+  maxCPU: 10
+  timeout: 2m
+  dropCaches: true
+`),
+			Config: &Config{
+				OCIs: []string{"runc"},
+				CRIs: []string{"containerd"},
+				Runs: 5,
+				Quiescence: &Quiescence{
+					MaxLoad:    0.5,
+					MaxCPU:     10,
+					Timeout:    2 * time.Minute,
+					DropCaches: true,
+				},
+			},
+		},
+		{
+			Name: "matrix",
+			Content: []byte(`
+oci: ["runc", "runsc"]
+cri: ["containerd", "crio"]
+filter:
+- performance.cpu
+runs: 10
+matrix:
+  exclude:
+  - cri: crio
+    oci: runc
+  include:
+  - cri: native
+    oci: host
+  overrides:
+  - cri: crio
+    oci: runsc
+    runs: 5
+  - oci: runsc
+    filter: ["scalability.runtime.5"]
+    params:
+      scale: 20
+`),
+			Config: &Config{
+				OCIs:   []string{"runc", "runsc"},
+				CRIs:   []string{"containerd", "crio"},
+				Filter: []string{"performance.cpu"},
+				Runs:   10,
+				Rules: MatrixRules{
+					Exclude: []benchmark.Combination{{CRI: "crio", OCI: "runc"}},
+					Include: []benchmark.Combination{{CRI: "native", OCI: "host"}},
+					Overrides: []benchmark.Override{
+						{Combination: benchmark.Combination{CRI: "crio", OCI: "runsc"}, Runs: 5},
+						{
+							Combination: benchmark.Combination{OCI: "runsc"},
+							Selection:   benchmark.Selection{Filter: []string{"scalability.runtime.5"}, Params: map[string]string{"scale": "20"}},
+						},
+					},
+				},
 			},
 		},
 	}
@@ -46,10 +116,11 @@ scale: 1
 				t.Fatalf("could not write to tmpfile: %v", err)
 			}
 			tmpFile.Close()
-			cfg, err := Parse(tmpFile.Name())
+			cfg, err := Parse(tmpFile.Name(), nil)
 			if err != nil {
 				t.Fatalf("could not parse config: %v", err)
 			}
+			tc.Config.Source = tmpFile.Name()
 			if !reflect.DeepEqual(cfg, tc.Config) {
 				t.Errorf("expected %v, got %v", tc.Config, cfg)
 			}
@@ -60,3 +131,144 @@ scale: 1
 	}
 
 }
+
+func TestConfigMatrix(t *testing.T) {
+	cfg := &Config{
+		OCIs:   []string{"runc", "runsc"},
+		CRIs:   []string{"containerd"},
+		Filter: []string{"performance.cpu"},
+		Runs:   10,
+		Rules: MatrixRules{
+			Overrides: []benchmark.Override{
+				{
+					Combination: benchmark.Combination{OCI: "runsc"},
+					Runs:        2,
+					Selection:   benchmark.Selection{Filter: []string{"scalability.runtime.5"}, Params: map[string]string{"scale": "20"}},
+				},
+			},
+		},
+	}
+	matrix, err := cfg.Matrix()
+	if err != nil {
+		t.Fatalf("could not build matrix: %v", err)
+	}
+	items, err := matrix.Plan(nil)
+	if err != nil {
+		t.Fatalf("could not plan matrix: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 plan items, got %+v", items)
+	}
+	if items[0].OCI != "runc" || items[0].Benchmark != "performance.cpu.time" || items[0].Runs != 10 {
+		t.Errorf("unexpected plan item %+v", items[0])
+	}
+	if items[1].OCI != "runsc" || items[1].Benchmark != "scalability.runtime.20" || items[1].Runs != 2 {
+		t.Errorf("unexpected plan item %+v", items[1])
+	}
+	cfg.Rules.Overrides[0].Params["scale"] = "many"
+	if _, err := cfg.Matrix(); err == nil {
+		t.Errorf("expected error for invalid param")
+	}
+}
+
+func TestConfigMatrixStackedOverrides(t *testing.T) {
+	cfg := &Config{
+		OCIs:   []string{"runc", "runsc"},
+		CRIs:   []string{"containerd", "crio"},
+		Filter: []string{"performance.cpu"},
+		Runs:   10,
+		Rules: MatrixRules{
+			Exclude: []benchmark.Combination{{CRI: "crio", OCI: "runc"}},
+			Overrides: []benchmark.Override{
+				{
+					Combination: benchmark.Combination{OCI: "runsc"},
+					Runs:        3,
+					Selection:   benchmark.Selection{Filter: []string{"scalability.runtime.10"}},
+				},
+				{
+					Combination: benchmark.Combination{CRI: "containerd"},
+					Selection:   benchmark.Selection{Params: map[string]string{"scale": "20"}},
+				},
+			},
+		},
+	}
+	matrix, err := cfg.Matrix()
+	if err != nil {
+		t.Fatalf("could not build matrix: %v", err)
+	}
+	items, err := matrix.Plan(nil)
+	if err != nil {
+		t.Fatalf("could not plan matrix: %v", err)
+	}
+	var planned []string
+	for _, item := range items {
+		planned = append(planned, fmt.Sprintf("%s/%s %s x%d", item.CRI, item.OCI, item.Benchmark, item.Runs))
+	}
+	// the params of the second override keep the filter of the first one
+	expected := []string{
+		"containerd/runc performance.cpu.time x10",
+		"containerd/runsc scalability.runtime.20 x3",
+		"crio/runsc scalability.runtime.10 x3",
+	}
+	if !reflect.DeepEqual(planned, expected) {
+		t.Errorf("expected plan %v, got %v", expected, planned)
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	runs, warmup, output := 2, 1, ""
+	cfg := &Config{
+		CRIs:    []string{"containerd", "crio"},
+		OCIs:    []string{"runc", "runsc"},
+		Filter:  []string{"performance"},
+		Exclude: []string{"performance.disk.*"},
+		Runs:    10,
+		Output:  "performance.json",
+	}
+	o := &Overrides{CRIs: []string{"containerd"}, Filter: []string{"performance.cpu"}, Runs: &runs, Warmup: &warmup, Output: &output}
+	if err := o.Apply(cfg); err != nil {
+		t.Fatalf("could not apply overrides: %v", err)
+	}
+	expected := &Config{
+		CRIs:   []string{"containerd"},
+		OCIs:   []string{"runc", "runsc"},
+		Filter: []string{"performance.cpu"},
+		Runs:   2,
+		Warmup: 1,
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %+v, got %+v", expected, cfg)
+	}
+	if err := (&Overrides{Filter: []string{"performance"}, Exclude: []string{"tag:io"}}).Apply(cfg); err != nil {
+		t.Fatalf("could not apply overrides: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Filter, []string{"performance"}) || !reflect.DeepEqual(cfg.Exclude, []string{"tag:io"}) {
+		t.Errorf("expected filter and exclude to be overridden, got %v and %v", cfg.Filter, cfg.Exclude)
+	}
+	runs = 0
+	if err := (&Overrides{Runs: &runs}).Apply(cfg); err == nil {
+		t.Errorf("expected error for invalid runs")
+	}
+}
+
+func TestMatrixEmptyFilter(t *testing.T) {
+	cfg := &Config{
+		CRIs:    []string{"containerd"},
+		OCIs:    []string{"runc"},
+		Exclude: []string{"performance"},
+		Runs:    1,
+	}
+	m, err := cfg.Matrix()
+	if err != nil {
+		t.Fatalf("could not create matrix: %v", err)
+	}
+	expected, _ := benchmark.Filter(suites.All(), nil, []string{"performance"})
+	if len(m.Items) == 0 || len(m.Items) != len(expected) {
+		t.Fatalf("expected all %d benchmarks except the excluded ones, got %d", len(expected), len(m.Items))
+	}
+	for _, item := range m.Items {
+		if strings.HasPrefix(item.Name(), "performance") {
+			t.Errorf("expected %s to be excluded", item.Name())
+		}
+	}
+}
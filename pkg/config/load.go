@@ -0,0 +1,187 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var separatorRegexp = regexp.MustCompile(`^---\s*(#.*)?$`)
+
+// document is a YAML document of a config file starting after the given number of lines.
+type document struct {
+	data   []byte
+	offset int
+}
+
+// splitDocuments splits the data at '---' lines. Documents without any content are skipped.
+func splitDocuments(data []byte) []document {
+	var (
+		docs    []document
+		current bytes.Buffer
+		offset  int
+		empty   = true
+	)
+	flush := func(next int) {
+		if !empty {
+			docs = append(docs, document{data: append([]byte{}, current.Bytes()...), offset: offset})
+		}
+		current.Reset()
+		offset, empty = next, true
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if separatorRegexp.MatchString(text) {
+			flush(line)
+			continue
+		}
+		if trimmed := strings.TrimSpace(text); trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			empty = false
+		}
+		current.WriteString(text)
+		current.WriteByte('\n')
+	}
+	flush(line)
+	return docs
+}
+
+// shift moves the lines of the config errors by the offset of their document.
+func shift(err error, offset int) error {
+	if errs, ok := err.(Errors); ok {
+		for _, e := range errs {
+			if e.Line > 0 {
+				e.Line += offset
+			}
+		}
+	}
+	return err
+}
+
+// read reads a config file and renders it if it is a template.
+func read(file string, vars Vars) ([]byte, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(file, TemplateExt) {
+		return Render(file, data, vars)
+	}
+	return data, nil
+}
+
+// Load reads and validates all documents of a config file. Templates ending in .yamlt are rendered
+// with the variables first. Each document is merged with the files it includes before validation.
+// Problems are reported as Errors pointing to their line in the file.
+func Load(file string, vars Vars) ([]*Config, error) {
+	data, err := read(file, vars)
+	if err != nil {
+		return nil, err
+	}
+	docs := splitDocuments(data)
+	if len(docs) == 0 {
+		return nil, &Error{File: file, Msg: "no config found"}
+	}
+	configs := make([]*Config, len(docs))
+	for i, doc := range docs {
+		config := &Config{}
+		if err := decode(file, doc.data, config); err != nil {
+			return nil, shift(err, doc.offset)
+		}
+		if _, err := config.resolve(file, doc.data, vars, []string{file}); err != nil {
+			return nil, err
+		}
+		if err := config.Validate(file, doc.data); err != nil {
+			return nil, shift(err, doc.offset)
+		}
+		config.Source = file
+		if len(docs) > 1 {
+			config.Source = fmt.Sprintf("%s#%d", file, i+1)
+		}
+		configs[i] = config
+	}
+	return configs, nil
+}
+
+// resolve merges the included files into the config decoded from the data. Later includes take
+// precedence over earlier ones and the config itself over all of them. The stack holds the files
+// being included. It returns the keys set by the config or its includes.
+func (c *Config) resolve(file string, data []byte, vars Vars, stack []string) (map[string]bool, error) {
+	present, err := keys(data)
+	if err != nil {
+		return nil, &Error{File: file, Msg: err.Error()}
+	}
+	includes := c.Include
+	c.Include = nil
+	for i := len(includes) - 1; i >= 0; i-- {
+		path := includes[i]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), path)
+		}
+		for _, parent := range stack {
+			if filepath.Clean(parent) == path {
+				return nil, &Error{File: file, Msg: fmt.Sprintf("include cycle: %s", strings.Join(append(stack, path), " -> "))}
+			}
+		}
+		data, err := read(path, vars)
+		if err != nil {
+			return nil, &Error{File: file, Msg: fmt.Sprintf("include: %v", err)}
+		}
+		docs := splitDocuments(data)
+		if len(docs) != 1 {
+			return nil, &Error{File: path, Msg: "included files must contain exactly one document"}
+		}
+		defaults := &Config{}
+		if err := decode(path, docs[0].data, defaults); err != nil {
+			return nil, shift(err, docs[0].offset)
+		}
+		inherited, err := defaults.resolve(path, docs[0].data, vars, append(stack, path))
+		if err != nil {
+			return nil, err
+		}
+		c.merge(defaults, present, inherited)
+	}
+	return present, nil
+}
+
+// keys returns the top-level keys of the YAML document.
+func keys(data []byte) (map[string]bool, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	present := make(map[string]bool, len(values))
+	for key := range values {
+		present[key] = true
+	}
+	return present, nil
+}
+
+// merge sets the fields whose keys are not present in the config to the defaults and adds the keys
+// set by the defaults to the present ones. Fields are replaced as a whole, so a quiescence section
+// or a list of the config replaces the included one, and a key set to zero or an empty list is kept.
+func (c *Config) merge(defaults *Config, present, inherited map[string]bool) {
+	dst, src := reflect.ValueOf(c).Elem(), reflect.ValueOf(defaults).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		key := strings.Split(dst.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "" || key == "-" || present[key] || !inherited[key] {
+			continue
+		}
+		dst.Field(i).Set(src.Field(i))
+		present[key] = true
+	}
+}
+
+// Effective returns the config with its includes resolved as YAML.
+func (c *Config) Effective() ([]byte, error) {
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "load_test")
+	if err != nil {
+		t.Fatalf("could not create tmpdir: %v", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("could not create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("could not write file: %v", err)
+		}
+	}
+	return dir
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"include/runtimes.yaml": `include: [base.yaml]
+cri: ["containerd", "crio"]
+oci: ["runc", "runsc"]
+`,
+		"include/base.yaml": `cri: ["containerd"]
+runs: 10
+quiescence:
+  maxLoad: 0.5
+  timeout: 1m
+`,
+		"suite.yaml": `# shared defaults
+include: [include/runtimes.yaml]
+filter: ["performance.cpu"]
+---
+---
+include: [include/runtimes.yaml]
+oci: ["runc"]
+runs: 3
+filter: ["operations"]
+output: operations.json
+`,
+	})
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "suite.yaml")
+	configs, err := Load(file, nil)
+	if err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	quiescence := &Quiescence{MaxLoad: 0.5, Timeout: time.Minute}
+	expected := []*Config{
+		{
+			CRIs:       []string{"containerd", "crio"},
+			OCIs:       []string{"runc", "runsc"},
+			Filter:     []string{"performance.cpu"},
+			Runs:       10,
+			Quiescence: quiescence,
+			Source:     file + "#1",
+		},
+		{
+			CRIs:       []string{"containerd", "crio"},
+			OCIs:       []string{"runc"},
+			Filter:     []string{"operations"},
+			Runs:       3,
+			Output:     "operations.json",
+			Quiescence: quiescence,
+			Source:     file + "#2",
+		},
+	}
+	if !reflect.DeepEqual(configs, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, configs)
+	}
+	if _, err := Parse(file, nil); err == nil {
+		t.Errorf("expected error parsing several documents")
+	}
+	// the effective config loads into the same config
+	effective, err := configs[1].Effective()
+	if err != nil {
+		t.Fatalf("could not encode config: %v", err)
+	}
+	if strings.Contains(string(effective), "include") {
+		t.Errorf("expected includes to be resolved, got\n%s", effective)
+	}
+	reloaded := &Config{}
+	if err := decode("effective.yaml", effective, reloaded); err != nil {
+		t.Fatalf("could not decode effective config: %v", err)
+	}
+	reloaded.Source = configs[1].Source
+	if !reflect.DeepEqual(reloaded, configs[1]) {
+		t.Errorf("expected effective config %+v, got %+v", configs[1], reloaded)
+	}
+}
+
+func TestLoadExplicitZero(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `cri: ["containerd"]
+oci: ["runc"]
+runs: 3
+warmup: 2
+filter: ["performance"]
+exclude: ["performance.disk"]
+`,
+		"middle.yaml": `include: [base.yaml]
+warmup: 0
+`,
+		"suite.yaml": `include: [middle.yaml]
+exclude: []
+`,
+	})
+	defer os.RemoveAll(dir)
+	configs, err := Load(filepath.Join(dir, "suite.yaml"), nil)
+	if err != nil {
+		t.Fatalf("could not load config: %v", err)
+	}
+	c := configs[0]
+	if c.Warmup != 0 || len(c.Exclude) != 0 {
+		t.Errorf("expected explicit zero warmup and empty exclude to be kept, got warmup %d, exclude %v", c.Warmup, c.Exclude)
+	}
+	if c.Runs != 3 || !reflect.DeepEqual(c.Filter, []string{"performance"}) {
+		t.Errorf("expected unset runs and filter from the include, got runs %d, filter %v", c.Runs, c.Filter)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": "include: [b.yaml]\n",
+		"b.yaml": "include: [a.yaml]\n",
+		"multi.yaml": `cri: ["containerd"]
+---
+cri: ["containerd"]
+`,
+		"lines.yaml": `cri: ["containerd"]
+oci: ["runc"]
+runs: 1
+---
+cri: ["containerd"]
+oci: ["runc"]
+runs: 0
+`,
+		"include.yaml": "include: [multi.yaml]\nruns: 1\n",
+	})
+	defer os.RemoveAll(dir)
+	tt := []struct {
+		File  string
+		Error string
+	}{
+		{"a.yaml", "include cycle"},
+		{"include.yaml", "included files must contain exactly one document"},
+		{"lines.yaml", "lines.yaml:7: runs must be larger than 0"},
+		{"missing.yaml", "no such file"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.File, func(t *testing.T) {
+			_, err := Load(filepath.Join(dir, tc.File), nil)
+			if err == nil || !strings.Contains(err.Error(), tc.Error) {
+				t.Errorf("expected error containing %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
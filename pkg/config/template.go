@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TemplateExt is the file extension of config templates.
+const TemplateExt = ".yamlt"
+
+// Vars are the variables available to config templates.
+type Vars map[string]interface{}
+
+// LoadVars reads variables from a YAML file.
+func LoadVars(file string) (Vars, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	vars := Vars{}
+	if err := yaml.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return vars, nil
+}
+
+// Set assigns variables from key=value pairs. Values are decoded as YAML,
+// so 'runs=5' yields a number and 'cri=[containerd, crio]' a list.
+func (v Vars) Set(assignments []string) error {
+	for _, assignment := range assignments {
+		i := strings.IndexByte(assignment, '=')
+		if i < 1 {
+			return fmt.Errorf("invalid assignment %q, expected key=value", assignment)
+		}
+		var value interface{}
+		if err := yaml.Unmarshal([]byte(assignment[i+1:]), &value); err != nil {
+			return fmt.Errorf("invalid value in %q: %v", assignment, err)
+		}
+		v[assignment[:i]] = value
+	}
+	return nil
+}
+
+var templateFuncs = template.FuncMap{
+	// env returns the value of an environment variable.
+	"env": os.Getenv,
+	// hostname returns the name of the host.
+	"hostname": func() string {
+		name, _ := os.Hostname()
+		return name
+	},
+	// default returns the value unless it is empty, e.g. {{ index . "runs" | default 10 }}.
+	"default": func(def, value interface{}) interface{} {
+		if value == nil {
+			return def
+		}
+		if v := reflect.ValueOf(value); v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return def
+		}
+		return value
+	},
+	// list creates a list from its arguments.
+	"list": func(values ...interface{}) []interface{} {
+		return values
+	},
+	// split splits a comma-separated string into a list.
+	"split": func(s string) []string {
+		return strings.Split(s, ",")
+	},
+	// json encodes a value as JSON, which is valid inline YAML.
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(jsonCompatible(value))
+		return string(data), err
+	},
+}
+
+// jsonCompatible converts the maps decoded by yaml.v2 so they can be encoded as JSON.
+func jsonCompatible(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(elem)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, elem := range v {
+			l[i] = jsonCompatible(elem)
+		}
+		return l
+	}
+	return value
+}
+
+// Render executes a config template with the variables.
+// Referencing an undefined variable with {{ .name }} is an error, optional variables use {{ index . "name" }}.
+func Render(file string, data []byte, vars Vars) ([]byte, error) {
+	if vars == nil {
+		vars = Vars{}
+	}
+	tmpl, err := template.New(filepath.Base(file)).Funcs(templateFuncs).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, map[string]interface{}(vars)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Template string
+		Set      []string
+		Expected string
+		Error    bool
+	}{
+		{
+			Name:     "defaults",
+			Template: `cri: {{ index . "cri" | default (list "containerd" "crio") | json }}, runs: {{ index . "runs" | default 10 }}`,
+			Expected: `cri: ["containerd","crio"], runs: 10`,
+		},
+		{
+			Name:     "set",
+			Template: `cri: {{ index . "cri" | default (list "containerd" "crio") | json }}, runs: {{ index . "runs" | default 10 }}`,
+			Set:      []string{"cri=[native]", "runs=3"},
+			Expected: `cri: ["native"], runs: 3`,
+		},
+		{
+			Name:     "split",
+			Template: `oci: {{ split .oci | json }}`,
+			Set:      []string{"oci=runc,runsc"},
+			Expected: `oci: ["runc","runsc"]`,
+		},
+		{
+			Name:     "missing",
+			Template: `output: {{ .output }}`,
+			Error:    true,
+		},
+		{
+			Name:     "syntax",
+			Template: `output: {{ .output`,
+			Error:    true,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			vars := Vars{}
+			if err := vars.Set(tc.Set); err != nil {
+				t.Fatalf("could not set vars: %v", err)
+			}
+			data, err := Render(tc.Name+TemplateExt, []byte(tc.Template), vars)
+			if tc.Error {
+				if err == nil {
+					t.Errorf("expected error, got %q", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not render: %v", err)
+			}
+			if string(data) != tc.Expected {
+				t.Errorf("expected %q, got %q", tc.Expected, data)
+			}
+		})
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	vars := Vars{}
+	if err := vars.Set([]string{"cri=[containerd]", "runs=2", "prefix=host1"}); err != nil {
+		t.Fatalf("could not set vars: %v", err)
+	}
+	cfg, err := Parse("../../suites/cpu.yamlt", vars)
+	if err != nil {
+		t.Fatalf("could not parse template: %v", err)
+	}
+	expected := &Config{
+		CRIs:   []string{"containerd"},
+		OCIs:   []string{"runc", "runsc"},
+		Filter: []string{"performance.cpu"},
+		Runs:   2,
+		Output: "host1.performance.json",
+		Source: "../../suites/cpu.yamlt",
+	}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("expected %v, got %v", expected, cfg)
+	}
+	if err := vars.Set([]string{"invalid"}); err == nil {
+		t.Errorf("expected error for assignment without value")
+	}
+}
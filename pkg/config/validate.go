@@ -0,0 +1,319 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/benchmark/suites"
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/lnsp/touchstone/pkg/util"
+	"gopkg.in/yaml.v2"
+)
+
+// Error is a problem found in a config file. Line is 0 if the position is unknown.
+// Lines of templates refer to the rendered config.
+type Error struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Msg)
+}
+
+// Errors lists all problems found in a config file.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+var (
+	yamlLineRegexp = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	handlerRegexp  = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// decode strictly decodes the config, rejecting unknown fields.
+func decode(file string, data []byte, config *Config) error {
+	err := yaml.UnmarshalStrict(data, config)
+	if err == nil {
+		return nil
+	}
+	msgs := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		msgs = typeErr.Errors
+	}
+	var errs Errors
+	for _, msg := range msgs {
+		e := &Error{File: file, Msg: msg}
+		if m := yamlLineRegexp.FindStringSubmatch(msg); m != nil {
+			e.Line, _ = strconv.Atoi(m[1])
+			e.Msg = m[2]
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// validator collects the problems of a config.
+type validator struct {
+	file string
+	data []byte
+	errs Errors
+}
+
+// errorf records a problem at the line of the value of the top-level key.
+// An empty value points to the key itself.
+func (v *validator) errorf(key, value, format string, args ...interface{}) {
+	v.errs = append(v.errs, &Error{
+		File: v.file,
+		Line: findLine(v.data, key, value),
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+// findLine returns the line of the first occurrence of the value in the section of the top-level key.
+// It returns the line of the key if the value is not found and 0 if the key is missing.
+func findLine(data []byte, key, value string) int {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	keyLine := 0
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		topLevel := len(text) > 0 && text[0] != ' ' && text[0] != '\t' && text[0] != '#' && text[0] != '-'
+		if keyLine == 0 {
+			if topLevel && strings.HasPrefix(text, key+":") {
+				keyLine = line
+			} else {
+				continue
+			}
+		} else if topLevel {
+			break
+		}
+		if value == "" {
+			return keyLine
+		}
+		if i := strings.Index(text, value); i >= 0 && (line > keyLine || i > len(key)) {
+			return line
+		}
+	}
+	return keyLine
+}
+
+// Validate checks the config for problems that would otherwise only surface while benchmarking.
+// It expects the registry of runtime backends to be populated.
+func (c *Config) Validate(file string, data []byte) error {
+	v := &validator{file: file, data: data}
+	if c.Runs < 1 {
+		v.errorf("runs", "", "runs must be larger than 0")
+	}
+	if c.Warmup < 0 {
+		v.errorf("warmup", "", "warmup must not be negative")
+	}
+	v.validateFilters("filter", "exclude", c.Filter, c.Exclude)
+	if len(c.CRIs) == 0 && len(c.Rules.Include) == 0 {
+		v.errorf("cri", "", "no CRI configured")
+	}
+	v.validateCRIs(c.CRIs)
+	v.validateOCIs(c.CRIs, c.OCIs)
+	v.validateRules(c)
+	if q := c.Quiescence; q != nil {
+		if q.MaxLoad < 0 || q.MaxCPU < 0 || q.MaxCPU > 100 {
+			v.errorf("quiescence", "", "quiescence thresholds must be positive and maxCPU at most 100")
+		}
+		if q.Timeout < 0 || q.Interval < 0 {
+			v.errorf("quiescence", "", "quiescence durations must not be negative")
+		}
+	}
+	if len(v.errs) > 0 {
+		return v.errs
+	}
+	return nil
+}
+
+// validateFilters checks that every filter selects at least one benchmark of the registry.
+// Problems are reported at the given top-level keys.
+func (v *validator) validateFilters(filterKey, excludeKey string, filter, exclude []string) bool {
+	all := suites.All()
+	valid := true
+	for _, expr := range filter {
+		selected, err := benchmark.Filter(all, []string{expr}, nil)
+		if err != nil {
+			v.errorf(filterKey, expr, "%v", err)
+			valid = false
+		} else if len(selected) == 0 {
+			v.errorf(filterKey, expr, "filter %q matches no benchmark, see 'touchstone list'", expr)
+			valid = false
+		}
+	}
+	for _, expr := range exclude {
+		if _, err := benchmark.NewSelector(nil, []string{expr}); err != nil {
+			v.errorf(excludeKey, expr, "%v", err)
+			valid = false
+		}
+	}
+	if !valid {
+		return false
+	}
+	if selected, _ := benchmark.Filter(all, filter, exclude); len(selected) == 0 {
+		v.errorf(excludeKey, "", "filter and exclude select no benchmark")
+		return false
+	}
+	return true
+}
+
+// validateCRIs checks that every CRI names a runtime backend or a CRI endpoint.
+func (v *validator) validateCRIs(cris []string) {
+	seen := make(map[string]bool)
+	for _, cri := range cris {
+		if seen[cri] {
+			v.errorf("cri", cri, "CRI %q listed twice", cri)
+		}
+		seen[cri] = true
+		if _, ok := runtime.LookupBackend(cri); ok || util.HasCRIEndpoint(cri) {
+			continue
+		}
+		v.errorf("cri", cri, "unknown CRI %q: no backend registered and no endpoint at %s", cri, util.GetCRIEndpoint(cri))
+	}
+}
+
+// validateOCIs checks that the handler names are well-formed and each is evaluated by at least one CRI.
+func (v *validator) validateOCIs(cris, ocis []string) {
+	seen := make(map[string]bool)
+	for _, oci := range ocis {
+		if seen[oci] {
+			v.errorf("oci", oci, "handler %q listed twice", oci)
+		}
+		seen[oci] = true
+		if !handlerRegexp.MatchString(oci) {
+			v.errorf("oci", oci, "invalid handler name %q", oci)
+			continue
+		}
+		supported := false
+		for _, cri := range cris {
+			for _, handler := range runtime.Handlers(cri, ocis) {
+				supported = supported || handler == oci
+			}
+		}
+		if !supported && len(cris) > 0 {
+			v.errorf("oci", oci, "handler %q is not supported by any of the CRIs %v", oci, cris)
+		}
+	}
+}
+
+// validateRules checks that the matrix rules refer to configured CRIs and handlers and match any combination.
+func (v *validator) validateRules(c *Config) {
+	cris, ocis := append([]string{}, c.CRIs...), append([]string{}, c.OCIs...)
+	for _, rule := range c.Rules.Include {
+		if rule.CRI == "" || rule.OCI == "" {
+			v.errorf("matrix", "include", "included combinations need a CRI and a handler")
+			continue
+		}
+		if !containsString(cris, rule.CRI) {
+			v.validateCRIs([]string{rule.CRI})
+		}
+		if !handlerRegexp.MatchString(rule.OCI) {
+			v.errorf("matrix", rule.OCI, "invalid handler name %q", rule.OCI)
+		} else if !containsString(runtime.Handlers(rule.CRI, []string{rule.OCI}), rule.OCI) {
+			v.errorf("matrix", rule.OCI, "include %s: handler %q is not supported by CRI %q", rule, rule.OCI, rule.CRI)
+		}
+		cris, ocis = append(cris, rule.CRI), append(ocis, rule.OCI)
+	}
+	matrix := &benchmark.Matrix{
+		CRIs:    c.CRIs,
+		OCIs:    c.OCIs,
+		Include: c.Rules.Include,
+	}
+	all := matrix.Combinations()
+	matrix.Exclude = c.Rules.Exclude
+	if len(c.CRIs) > 0 && len(matrix.Combinations()) == 0 {
+		v.errorf("oci", "", "no combination of CRI and handler left to evaluate")
+	}
+	for _, rule := range c.Rules.Exclude {
+		v.validateRule("exclude", rule, cris, ocis, all)
+	}
+	matrix.Registry, matrix.Selection = suites.Registry, c.Selection()
+	combinations := matrix.Combinations()
+	for i, o := range c.Rules.Overrides {
+		v.validateRule("override", o.Combination, cris, ocis, all)
+		if o.Runs < 0 {
+			v.errorf("matrix", "runs", "runs of override must not be negative")
+		}
+		if o.Selection.IsZero() {
+			continue
+		}
+		selection := c.Selection().Merge(o.Selection)
+		if !v.validateFilters("matrix", "matrix", selection.Filter, selection.Exclude) {
+			continue
+		}
+		// the override applies on top of the earlier ones matching the same combinations
+		matrix.Overrides = c.Rules.Overrides[:i+1]
+		v.validateParams(o, matrix, combinations)
+	}
+}
+
+// validateRule checks that the rule refers to configured CRIs and handlers and matches a combination.
+func (v *validator) validateRule(kind string, rule benchmark.Combination, cris, ocis []string, all []benchmark.Combination) {
+	if rule.CRI != "" && !containsString(cris, rule.CRI) {
+		v.errorf("matrix", rule.CRI, "%s refers to unconfigured CRI %q", kind, rule.CRI)
+		return
+	}
+	if rule.OCI != "" && !containsString(ocis, rule.OCI) {
+		v.errorf("matrix", rule.OCI, "%s refers to unconfigured handler %q", kind, rule.OCI)
+		return
+	}
+	for _, c := range all {
+		if rule.Matches(c) {
+			return
+		}
+	}
+	v.errorf("matrix", rule.OCI, "%s %s matches no combination", kind, rule)
+}
+
+// validateParams checks that every parameter is declared by a benchmark the override selects for any of
+// the combinations it matches, and has a valid value.
+func (v *validator) validateParams(o benchmark.Override, matrix *benchmark.Matrix, combinations []benchmark.Combination) {
+	var items []benchmark.Benchmark
+	for _, combination := range combinations {
+		if !o.Matches(combination) {
+			continue
+		}
+		selected, _, err := matrix.Settings(combination)
+		if err != nil {
+			v.errorf("matrix", "params", "%v", err)
+			return
+		}
+		items = append(items, selected...)
+	}
+	for key := range o.Params {
+		declared := false
+		for _, item := range items {
+			_, ok := benchmark.Params(item)[key]
+			declared = declared || ok
+		}
+		if !declared {
+			v.errorf("matrix", key, "no benchmark of the override declares the param %q", key)
+		}
+	}
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
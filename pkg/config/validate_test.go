@@ -0,0 +1,140 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/lnsp/touchstone/pkg/runtime/docker"
+	_ "github.com/lnsp/touchstone/pkg/runtime/native"
+	_ "github.com/lnsp/touchstone/pkg/runtime/ocidirect"
+)
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		Name    string
+		Content string
+		Errors  []string
+	}{
+		{
+			Name: "valid",
+			Content: `cri: ["containerd", "native"]
+oci: ["runc", "unshare"]
+filter:
+- tag:startup
+runs: 1
+`,
+		},
+		{
+			Name: "unknown field",
+			Content: `cri: ["containerd"]
+oci: ["runc"]
+runs: 1
+scale: 1
+`,
+			Errors: []string{"config.yaml:4: field scale not found"},
+		},
+		{
+			Name: "syntax",
+			Content: `cri: ["containerd"
+runs: 1
+`,
+			Errors: []string{"config.yaml:1: did not find expected ',' or ']'"},
+		},
+		{
+			Name: "filter",
+			Content: `cri: ["containerd"]
+oci: ["runc"]
+filter:
+- performance.cpu
+- performance.gpu
+- re:[
+runs: 1
+`,
+			Errors: []string{
+				`config.yaml:5: filter "performance.gpu" matches no benchmark`,
+				`config.yaml:6: invalid expression "re:["`,
+			},
+		},
+		{
+			Name: "runtimes",
+			Content: `cri:
+- containerd
+- containred
+oci: ["runc", "run sc", "unshare"]
+runs: 0
+`,
+			Errors: []string{
+				`config.yaml:5: runs must be larger than 0`,
+				`config.yaml:3: unknown CRI "containred"`,
+				`config.yaml:4: invalid handler name "run sc"`,
+				`config.yaml:4: handler "unshare" is not supported by any of the CRIs`,
+			},
+		},
+		{
+			Name: "matrix",
+			Content: `cri: ["containerd", "crio"]
+oci: ["runc", "runsc"]
+runs: 1
+matrix:
+  exclude:
+  - cri: docker
+  include:
+  - cri: native
+  - cri: native
+    oci: runsc
+  overrides:
+  - oci: runsc
+    params:
+      size: 3
+`,
+			Errors: []string{
+				`config.yaml:7: included combinations need a CRI and a handler`,
+				`config.yaml:10: include native/runsc: handler "runsc" is not supported by CRI "native"`,
+				`config.yaml:6: exclude refers to unconfigured CRI "docker"`,
+				`config.yaml:14: no benchmark of the override declares the param "size"`,
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := &Config{}
+			err := decode("config.yaml", []byte(tc.Content), config)
+			if err == nil {
+				err = config.Validate("config.yaml", []byte(tc.Content))
+			}
+			if len(tc.Errors) == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			errs, ok := err.(Errors)
+			if !ok {
+				t.Fatalf("expected Errors, got %v", err)
+			}
+			if len(errs) != len(tc.Errors) {
+				t.Fatalf("expected %d errors, got %d:\n%v", len(tc.Errors), len(errs), errs)
+			}
+			for i, expected := range tc.Errors {
+				if !strings.HasPrefix(errs[i].Error(), expected) {
+					t.Errorf("expected error starting with %q, got %q", expected, errs[i].Error())
+				}
+			}
+		})
+	}
+}
+
+func TestSuites(t *testing.T) {
+	files, err := filepath.Glob("../../suites/*.yaml*")
+	if err != nil {
+		t.Fatalf("could not glob suites: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if _, err := Load(file, nil); err != nil {
+				t.Errorf("invalid suite: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package environment collects metadata about the host and container runtimes a benchmark runs on.
+package environment
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/lnsp/touchstone/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+const handlerVersionTimeout = 5 * time.Second
+
+// Manifest describes the environment that produced a set of results.
+type Manifest struct {
+	Touchstone string    `json:"touchstone"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Host       Host      `json:"host"`
+	Runtimes   []Runtime `json:"runtimes"`
+	Handlers   []Handler `json:"handlers"`
+}
+
+// Host describes the machine running the benchmarks.
+type Host struct {
+	Hostname      string `json:"hostname"`
+	OS            string `json:"os"`
+	Kernel        string `json:"kernel"`
+	KernelVersion string `json:"kernelVersion"`
+	Arch          string `json:"arch"`
+	CPUModel      string `json:"cpuModel"`
+	CPUs          int    `json:"cpus"`
+	MemoryTotal   uint64 `json:"memoryTotal"`
+	CgroupVersion int    `json:"cgroupVersion"`
+}
+
+// Runtime describes a CRI endpoint and the status it reported.
+// APIVersion is the version reported by the runtime, CRIVersion the one negotiated by the client.
+type Runtime struct {
+	CRI        string            `json:"cri"`
+	Endpoint   string            `json:"endpoint,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Version    string            `json:"version,omitempty"`
+	APIVersion string            `json:"apiVersion,omitempty"`
+	CRIVersion string            `json:"criVersion,omitempty"`
+	Conditions []Condition       `json:"conditions,omitempty"`
+	Info       map[string]string `json:"info,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Condition is a runtime condition reported by the CRI status call.
+type Condition struct {
+	Type    string `json:"type"`
+	Status  bool   `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Handler describes an OCI runtime handler and the version of its binary.
+// Handlers provided by a runtime backend have no binary and name their backend instead.
+type Handler struct {
+	Name    string `json:"name"`
+	Path    string `json:"path,omitempty"`
+	Version string `json:"version,omitempty"`
+	Backend string `json:"backend,omitempty"`
+}
+
+// Collect gathers the environment manifest for the given CRIs and OCI handlers.
+// Unreachable runtimes are recorded with their error instead of failing.
+func Collect(version string, cris, ocis []string) *Manifest {
+	manifest := &Manifest{
+		Touchstone: version,
+		CreatedAt:  time.Now().UTC(),
+		Host:       CollectHost(),
+	}
+	for _, cri := range cris {
+		manifest.Runtimes = append(manifest.Runtimes, CollectRuntime(cri))
+	}
+	for _, oci := range ocis {
+		manifest.Handlers = append(manifest.Handlers, CollectHandler(oci))
+	}
+	return manifest
+}
+
+// CollectHost reads the host metadata from /proc and /sys.
+func CollectHost() Host {
+	host := Host{
+		Arch:          goruntime.GOARCH,
+		CPUs:          goruntime.NumCPU(),
+		Kernel:        readTrimmed("/proc/sys/kernel/osrelease"),
+		KernelVersion: readTrimmed("/proc/version"),
+		OS:            osRelease("/etc/os-release"),
+		CPUModel:      procField("/proc/cpuinfo", "model name"),
+		MemoryTotal:   memTotal("/proc/meminfo"),
+		CgroupVersion: 1,
+	}
+	host.Hostname, _ = os.Hostname()
+	if _, err := os.Stat("/sys/fs/cgroup/cgroup.controllers"); err == nil {
+		host.CgroupVersion = 2
+	}
+	return host
+}
+
+// CollectRuntime queries version and status of the CRI endpoint.
+func CollectRuntime(cri string) Runtime {
+	rt := Runtime{CRI: cri}
+	if _, ok := runtime.LookupBackend(cri); !ok {
+		rt.Endpoint = util.GetCRIEndpoint(cri)
+	}
+	client, err := runtime.Dial(cri)
+	if err != nil {
+		rt.Error = err.Error()
+		return rt
+	}
+	defer client.Close()
+	if c, ok := client.(*runtime.Client); ok {
+		rt.CRIVersion = c.APIVersion()
+	}
+	version, err := client.VersionInfo()
+	if err != nil {
+		rt.Error = err.Error()
+		return rt
+	}
+	rt.Name = version.RuntimeName
+	rt.Version = version.RuntimeVersion
+	rt.APIVersion = version.RuntimeApiVersion
+	status, err := client.RuntimeStatus(true)
+	if err != nil {
+		logrus.WithError(err).WithField("cri", cri).Warn("failed to fetch runtime status")
+		return rt
+	}
+	rt.Info = status.Info
+	if status.Status != nil {
+		for _, c := range status.Status.Conditions {
+			rt.Conditions = append(rt.Conditions, Condition{
+				Type:    c.Type,
+				Status:  c.Status,
+				Reason:  c.Reason,
+				Message: c.Message,
+			})
+		}
+	}
+	return rt
+}
+
+// CollectHandler looks up the binary of an OCI runtime handler and asks it for its version.
+// Handlers of runtime backends are not looked up, as binaries of the same name are unrelated tools.
+func CollectHandler(oci string) Handler {
+	handler := Handler{Name: oci}
+	if backend, ok := runtime.HandlerBackend(oci); ok {
+		handler.Backend = backend
+		return handler
+	}
+	path, err := exec.LookPath(oci)
+	if err != nil {
+		return handler
+	}
+	handler.Path = path
+	ctx, cancel := context.WithTimeout(context.Background(), handlerVersionTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		logrus.WithError(err).WithField("oci", oci).Warn("failed to fetch handler version")
+		return handler
+	}
+	handler.Version = strings.TrimSpace(string(bytes.SplitN(out, []byte("\n"), 2)[0]))
+	return handler
+}
+
+func readTrimmed(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// procField returns the value of the first 'key: value' line with the given key.
+func procField(file, key string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == key {
+			return strings.TrimSpace(parts[1])
+		}
+	}
+	return ""
+}
+
+// memTotal returns the total memory in bytes reported by the meminfo file in kB, or 0 if it is unavailable.
+func memTotal(file string) uint64 {
+	fields := strings.Fields(procField(file, "MemTotal"))
+	if len(fields) == 0 {
+		return 0
+	}
+	kb, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return kb * 1024
+}
+
+// osRelease returns the pretty name of the distribution.
+func osRelease(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value := strings.TrimPrefix(scanner.Text(), "PRETTY_NAME="); value != scanner.Text() {
+			return strings.Trim(value, `"`)
+		}
+	}
+	return ""
+}
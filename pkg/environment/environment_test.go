@@ -0,0 +1,136 @@
+package environment
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestProcField(t *testing.T) {
+	tt := []struct {
+		Name, File, Key, Value string
+	}{
+		{"cpu-model", "testdata/cpuinfo", "model name", "Intel(R) Core(TM) i7-8700 CPU @ 3.20GHz"},
+		{"first-match", "testdata/cpuinfo", "processor", "0"},
+		{"meminfo", "testdata/meminfo", "MemAvailable", "28672000 kB"},
+		{"missing-key", "testdata/meminfo", "SwapTotal", ""},
+		{"missing-file", "testdata/missing", "MemTotal", ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			if value := procField(tc.File, tc.Key); value != tc.Value {
+				t.Errorf("expected %q, got %q", tc.Value, value)
+			}
+		})
+	}
+}
+
+func TestMemTotal(t *testing.T) {
+	tt := []struct {
+		File  string
+		Total uint64
+	}{
+		{"testdata/meminfo", 32768000 * 1024},
+		{"testdata/cpuinfo", 0},
+		{"testdata/missing", 0},
+	}
+	for _, tc := range tt {
+		if total := memTotal(tc.File); total != tc.Total {
+			t.Errorf("expected %d bytes of %s, got %d", tc.Total, tc.File, total)
+		}
+	}
+}
+
+func TestOSRelease(t *testing.T) {
+	tt := []struct {
+		File, Name string
+	}{
+		{"testdata/os-release", "Ubuntu 18.04.3 LTS"},
+		{"testdata/os-release-unquoted", "Arch Linux"},
+		{"testdata/os-release-minimal", ""},
+		{"testdata/missing", ""},
+	}
+	for _, tc := range tt {
+		if name := osRelease(tc.File); name != tc.Name {
+			t.Errorf("expected %q of %s, got %q", tc.Name, tc.File, name)
+		}
+	}
+}
+
+// fakeRuntime answers the version and status queries of CollectRuntime.
+type fakeRuntime struct {
+	runtime.Runtime
+	versionErr error
+	closed     bool
+}
+
+func (rt *fakeRuntime) VersionInfo() (*runtimeapi.VersionResponse, error) {
+	if rt.versionErr != nil {
+		return nil, rt.versionErr
+	}
+	return &runtimeapi.VersionResponse{RuntimeName: "fake", RuntimeVersion: "1.2.3", RuntimeApiVersion: "v1alpha2"}, nil
+}
+
+func (rt *fakeRuntime) RuntimeStatus(verbose bool) (*runtimeapi.StatusResponse, error) {
+	return &runtimeapi.StatusResponse{
+		Status: &runtimeapi.RuntimeStatus{
+			Conditions: []*runtimeapi.RuntimeCondition{{Type: "RuntimeReady", Status: true}},
+		},
+		Info: map[string]string{"config": "{}"},
+	}, nil
+}
+
+func (rt *fakeRuntime) Close() { rt.closed = true }
+
+func TestCollectRuntime(t *testing.T) {
+	succeeding := &fakeRuntime{}
+	failing := &fakeRuntime{versionErr: errors.New("connection refused")}
+	runtime.Register("environment-test-ok", runtime.Backend{
+		Dial: func() (runtime.Runtime, error) { return succeeding, nil },
+	})
+	runtime.Register("environment-test-failed", runtime.Backend{
+		Dial: func() (runtime.Runtime, error) { return failing, nil },
+	})
+	tt := []struct {
+		Name    string
+		CRI     string
+		Client  *fakeRuntime
+		Runtime Runtime
+	}{
+		{"success", "environment-test-ok", succeeding, Runtime{
+			CRI:        "environment-test-ok",
+			Name:       "fake",
+			Version:    "1.2.3",
+			APIVersion: "v1alpha2",
+			Conditions: []Condition{{Type: "RuntimeReady", Status: true}},
+			Info:       map[string]string{"config": "{}"},
+		}},
+		{"failure", "environment-test-failed", failing, Runtime{
+			CRI:   "environment-test-failed",
+			Error: "connection refused",
+		}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			rt := CollectRuntime(tc.CRI)
+			if !reflect.DeepEqual(rt, tc.Runtime) {
+				t.Errorf("expected %+v, got %+v", tc.Runtime, rt)
+			}
+			if !tc.Client.closed {
+				t.Errorf("expected client to be closed")
+			}
+		})
+	}
+}
+
+func TestCollectHandler(t *testing.T) {
+	runtime.Register("environment-test-handlers", runtime.Backend{Handlers: []string{"environment-test-host"}})
+	handler := CollectHandler("environment-test-host")
+	expected := Handler{Name: "environment-test-host", Backend: "environment-test-handlers"}
+	if !reflect.DeepEqual(handler, expected) {
+		t.Errorf("expected %+v, got %+v", expected, handler)
+	}
+}
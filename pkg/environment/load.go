@@ -0,0 +1,94 @@
+package environment
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// LoadAverage returns the 1-minute load average of the host.
+func LoadAverage() (float64, error) {
+	data, err := ioutil.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, errors.New("empty /proc/loadavg")
+	}
+	return strconv.ParseFloat(fields[0], 64)
+}
+
+// CPUUtilization samples /proc/stat twice and returns the percentage of non-idle CPU time in between.
+func CPUUtilization(interval time.Duration) (float64, error) {
+	busy1, total1, err := cpuTimes()
+	if err != nil {
+		return 0, err
+	}
+	time.Sleep(interval)
+	busy2, total2, err := cpuTimes()
+	if err != nil {
+		return 0, err
+	}
+	if total2 <= total1 {
+		return 0, nil
+	}
+	return 100 * float64(busy2-busy1) / float64(total2-total1), nil
+}
+
+// cpuTimes returns the busy and total jiffies of the aggregated cpu line.
+func cpuTimes() (busy, total uint64, err error) {
+	data, err := ioutil.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	line := strings.SplitN(string(data), "\n", 2)[0]
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected /proc/stat line %q", line)
+	}
+	var idle uint64
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += value
+		// idle and iowait
+		if i == 3 || i == 4 {
+			idle += value
+		}
+	}
+	return total - idle, total, nil
+}
+
+// DropCaches writes back dirty pages and drops the page cache, dentries and inodes.
+// It requires root privileges.
+func DropCaches() error {
+	syscall.Sync()
+	return ioutil.WriteFile("/proc/sys/vm/drop_caches", []byte("3"), 0200)
+}
+
+// Governors returns the CPU frequency scaling governor of each CPU.
+// Hosts without cpufreq support return an empty map.
+func Governors() (map[string]string, error) {
+	files, err := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_governor")
+	if err != nil {
+		return nil, err
+	}
+	governors := make(map[string]string, len(files))
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		cpu := filepath.Base(filepath.Dir(filepath.Dir(file)))
+		governors[cpu] = strings.TrimSpace(string(data))
+	}
+	return governors, nil
+}
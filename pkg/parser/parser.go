@@ -0,0 +1,230 @@
+// Package parser extracts numeric values from benchmark output.
+package parser
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNotFound is returned if the value is missing from the output.
+	ErrNotFound = errors.New("value not found")
+	// ErrMalformed is returned if the value can not be converted into a number.
+	ErrMalformed = errors.New("malformed value")
+	// ErrUnknownMetric is returned by extractors of metrics they do not know.
+	ErrUnknownMetric = errors.New("unknown metric")
+)
+
+// Error describes a failed extraction and keeps the offending output.
+type Error struct {
+	Key    string
+	Err    error
+	Output []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed to extract %s: %v", e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Log returns the output the extraction failed on.
+func (e *Error) Log() []byte {
+	return e.Output
+}
+
+// Extractor retrieves a single value from benchmark output.
+type Extractor interface {
+	Extract(data []byte) (float64, error)
+}
+
+// ExtractorFunc is an adapter to use ordinary functions as extractors.
+type ExtractorFunc func(data []byte) (float64, error)
+
+func (fn ExtractorFunc) Extract(data []byte) (float64, error) {
+	return fn(data)
+}
+
+// Fields maps report labels to the extractors of their values.
+type Fields map[string]Extractor
+
+// Extract applies all extractors to the output. It fails with an *Error on the first missing or malformed value.
+func Extract(data []byte, fields Fields) (map[string]float64, error) {
+	values := make(map[string]float64, len(fields))
+	for key, extractor := range fields {
+		value, err := extractor.Extract(data)
+		if err != nil {
+			return nil, &Error{Key: key, Err: err, Output: data}
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+var numberPattern = regexp.MustCompile(`^[-+]?(\d+\.?\d*|\.\d+)([eE][-+]?\d+)?`)
+
+// magnitudes maps the bare magnitude suffixes of values like "1.5K" to their factor.
+var magnitudes = map[string]float64{
+	"K": 1e3,
+	"k": 1e3,
+	"M": 1e6,
+	"G": 1e9,
+	"T": 1e12,
+}
+
+// ParseQuantity parses the leading number of s and returns it together with the trailing unit, e.g. "ms" of "1.5ms".
+func ParseQuantity(s string) (float64, string, error) {
+	s = strings.TrimSpace(s)
+	match := numberPattern.FindString(s)
+	if match == "" {
+		return 0, "", fmt.Errorf("%w: %q", ErrMalformed, s)
+	}
+	value, err := strconv.ParseFloat(match, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("%w: %q", ErrMalformed, s)
+	}
+	return value, strings.TrimSpace(s[len(match):]), nil
+}
+
+// ParseValue parses the leading number of s. Bare magnitude suffixes like "1.5K" are applied to the value,
+// other units like 's' or 'ms' are ignored; use ParseQuantity to inspect them.
+func ParseValue(s string) (float64, error) {
+	value, unit, err := ParseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if factor, ok := magnitudes[unit]; ok {
+		value *= factor
+	}
+	return value, nil
+}
+
+// FindPrefixed returns the remainder of the first line starting with prefix, ignoring surrounding whitespace.
+func FindPrefixed(data []byte, prefix string) (string, error) {
+	lines := strings.Split(string(data), "\n")
+	for _, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		if strings.HasPrefix(trimmed, prefix) {
+			return strings.TrimSpace(strings.TrimPrefix(trimmed, prefix)), nil
+		}
+	}
+	return "", fmt.Errorf("%w: prefix %q", ErrNotFound, prefix)
+}
+
+// Prefix extracts the value following the first line starting with prefix.
+func Prefix(prefix string) Extractor {
+	return ExtractorFunc(func(data []byte) (float64, error) {
+		value, err := FindPrefixed(data, prefix)
+		if err != nil {
+			return 0, err
+		}
+		return ParseValue(value)
+	})
+}
+
+// Regexp extracts the first submatch of the expression, or the whole match if it has no groups.
+func Regexp(expr string) Extractor {
+	re := regexp.MustCompile(expr)
+	return ExtractorFunc(func(data []byte) (float64, error) {
+		match := re.FindSubmatch(data)
+		if match == nil {
+			return 0, fmt.Errorf("%w: pattern %q", ErrNotFound, expr)
+		}
+		if len(match) > 1 {
+			return ParseValue(string(match[1]))
+		}
+		return ParseValue(string(match[0]))
+	})
+}
+
+// JSON extracts the number at the dot-separated path, e.g. "results.0.latency".
+func JSON(path string) Extractor {
+	return ExtractorFunc(func(data []byte) (float64, error) {
+		var doc interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		for _, key := range strings.Split(path, ".") {
+			switch node := doc.(type) {
+			case map[string]interface{}:
+				value, ok := node[key]
+				if !ok {
+					return 0, fmt.Errorf("%w: path %q", ErrNotFound, path)
+				}
+				doc = value
+			case []interface{}:
+				i, err := strconv.Atoi(key)
+				if err != nil || i < 0 || i >= len(node) {
+					return 0, fmt.Errorf("%w: path %q", ErrNotFound, path)
+				}
+				doc = node[i]
+			default:
+				return 0, fmt.Errorf("%w: path %q", ErrNotFound, path)
+			}
+		}
+		switch value := doc.(type) {
+		case float64:
+			return value, nil
+		case string:
+			return ParseValue(value)
+		}
+		return 0, fmt.Errorf("%w: path %q is not a number", ErrMalformed, path)
+	})
+}
+
+// CSV extracts the value of the named column in the given data row.
+// Negative rows are counted from the end, so -1 selects the last row.
+func CSV(column string, row int) Extractor {
+	return ExtractorFunc(func(data []byte) (float64, error) {
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.TrimLeadingSpace = true
+		records, err := reader.ReadAll()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if len(records) < 2 {
+			return 0, fmt.Errorf("%w: no rows", ErrNotFound)
+		}
+		index := -1
+		for i, name := range records[0] {
+			if name == column {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return 0, fmt.Errorf("%w: column %q", ErrNotFound, column)
+		}
+		rows := records[1:]
+		if row < 0 {
+			row += len(rows)
+		}
+		if row < 0 || row >= len(rows) || index >= len(rows[row]) {
+			return 0, fmt.Errorf("%w: row %d", ErrNotFound, row)
+		}
+		return ParseValue(rows[row][index])
+	})
+}
+
+// First returns the value of the first extractor that succeeds.
+func First(extractors ...Extractor) Extractor {
+	return ExtractorFunc(func(data []byte) (float64, error) {
+		err := error(ErrNotFound)
+		for _, extractor := range extractors {
+			var value float64
+			value, err = extractor.Extract(data)
+			if err == nil {
+				return value, nil
+			}
+		}
+		return 0, err
+	})
+}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindPrefixed(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Output []byte
+		Filter string
+		Value  string
+	}{
+		{
+			Name: "cpu",
+			Output: []byte(`sysbench 0.4.12:  multi-threaded system evaluation benchmark
+
+Running the test with following options:
+Number of threads: 1
+
+Doing CPU performance benchmark
+
+Threads started!
+Done.
+
+Maximum prime number checked in CPU test: 10000
+
+
+Test execution summary:
+    total time:                          10.0634s
+    total number of events:              10000
+    total time taken by event execution: 10.0610
+    per-request statistics:
+         min:                                  0.86ms
+         avg:                                  1.01ms
+         max:                                  2.91ms
+         approx.  95 percentile:               1.33ms
+
+Threads fairness:
+    events (avg/stddev):           10000.0000/0.00
+    execution time (avg/stddev):   10.0610/0.00
+`),
+			Value:  "10.0634s",
+			Filter: "total time:",
+		},
+		{
+			Name: "memory",
+			Output: []byte(`sysbench 0.4.12:  multi-threaded system evaluation benchmark
+
+	Running the test with following options:
+	Number of threads: 1
+	
+	Doing memory operations speed test
+	Memory block size: 1024K
+	
+	Memory transfer size: 102400M
+	
+	Memory operations type: write
+	Memory scope type: global
+	Threads started!
+	Done.
+	
+	Operations performed: 102400 (10221.21 ops/sec)
+	
+	102400.00 MB transferred (10221.21 MB/sec)
+	
+	
+	Test execution summary:
+		total time:                          10.0184s
+		total number of events:              102400
+		total time taken by event execution: 10.0103
+		per-request statistics:
+			 min:                                  0.09ms
+			 avg:                                  0.10ms
+			 max:                                  1.53ms
+			 approx.  95 percentile:               0.13ms
+	
+	Threads fairness:
+		events (avg/stddev):           102400.0000/0.00
+		execution time (avg/stddev):   10.0103/0.00
+`),
+			Filter: "total time:",
+			Value:  "10.0184s",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			result, err := FindPrefixed(tc.Output, tc.Filter)
+			if err != nil {
+				t.Fatalf("could not find prefix: %v", err)
+			}
+			if result != tc.Value {
+				t.Errorf("expected %s, got %s", tc.Value, result)
+			}
+		})
+	}
+}
+
+func TestParseQuantity(t *testing.T) {
+	tt := []struct {
+		Input string
+		Value float64
+		Unit  string
+	}{
+		{"10.0634s", 10.0634, "s"},
+		{" 1.5 K", 1.5, "K"},
+		{"83.139Mb/sec", 83.139, "Mb/sec"},
+		{"42", 42, ""},
+	}
+	for _, tc := range tt {
+		value, unit, err := ParseQuantity(tc.Input)
+		if err != nil {
+			t.Fatalf("could not parse %q: %v", tc.Input, err)
+		}
+		if value != tc.Value || unit != tc.Unit {
+			t.Errorf("expected %v %q of %q, got %v %q", tc.Value, tc.Unit, tc.Input, value, unit)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Output []byte
+		Fields Fields
+		Values map[string]float64
+		Err    error
+	}{
+		{
+			Name:   "prefix",
+			Output: []byte("    total time:                          10.0634s\n"),
+			Fields: Fields{"TotalTime": Prefix("total time:")},
+			Values: map[string]float64{"TotalTime": 10.0634},
+		},
+		{
+			Name:   "regexp",
+			Output: []byte("102400.00 MB transferred (10221.21 MB/sec)"),
+			Fields: Fields{"Throughput": Regexp(`\(([\d.]+) MB/sec\)`)},
+			Values: map[string]float64{"Throughput": 10221.21},
+		},
+		{
+			Name:   "json",
+			Output: []byte(`{"results": [{"latency": "1.5ms"}, {"latency": 2}]}`),
+			Fields: Fields{"First": JSON("results.0.latency"), "Second": JSON("results.1.latency")},
+			Values: map[string]float64{"First": 1.5, "Second": 2},
+		},
+		{
+			Name:   "csv",
+			Output: []byte("time, events\n1, 100\n2, 120\n"),
+			Fields: Fields{"First": CSV("events", 0), "Last": CSV("events", -1)},
+			Values: map[string]float64{"First": 100, "Last": 120},
+		},
+		{
+			Name:   "sysbench",
+			Output: []byte("Latency (ms):\n         min:    0.86\n         95th percentile:    1.33\n"),
+			Fields: Fields{"Min": Sysbench(SysbenchLatencyMin), "P95": Sysbench(SysbenchLatency95)},
+			Values: map[string]float64{"Min": 0.86, "P95": 1.33},
+		},
+		{
+			Name:   "magnitude",
+			Output: []byte("requests: 1.5K\nlatency: 1.5ms\n"),
+			Fields: Fields{"Requests": Prefix("requests:"), "Latency": Prefix("latency:")},
+			Values: map[string]float64{"Requests": 1500, "Latency": 1.5},
+		},
+		{
+			Name:   "unknown sysbench metric",
+			Output: []byte("total time: 10.0s\n"),
+			Fields: Fields{"Metric": Sysbench("throughput")},
+			Err:    ErrUnknownMetric,
+		},
+		{
+			Name:   "missing",
+			Output: []byte("FATAL: unknown option\n"),
+			Fields: Fields{"TotalTime": Prefix("total time:")},
+			Err:    ErrNotFound,
+		},
+		{
+			Name:   "malformed",
+			Output: []byte("total time: n/a\n"),
+			Fields: Fields{"TotalTime": Prefix("total time:")},
+			Err:    ErrMalformed,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			values, err := Extract(tc.Output, tc.Fields)
+			if tc.Err != nil {
+				var parseErr *Error
+				if !errors.As(err, &parseErr) || !errors.Is(err, tc.Err) {
+					t.Fatalf("expected %v, got %v", tc.Err, err)
+				}
+				if string(parseErr.Log()) != string(tc.Output) {
+					t.Errorf("expected output to be kept, got %q", parseErr.Log())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not extract values: %v", err)
+			}
+			for key, value := range tc.Values {
+				if values[key] != value {
+					t.Errorf("expected %s to be %v, got %v", key, value, values[key])
+				}
+			}
+		})
+	}
+}
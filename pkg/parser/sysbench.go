@@ -0,0 +1,39 @@
+package parser
+
+import "fmt"
+
+// Sysbench metrics understood by the Sysbench extractor.
+const (
+	SysbenchTotalTime  = "total time"
+	SysbenchEvents     = "events"
+	SysbenchLatencyMin = "latency min"
+	SysbenchLatencyAvg = "latency avg"
+	SysbenchLatencyMax = "latency max"
+	SysbenchLatency95  = "latency 95th percentile"
+)
+
+// sysbenchPrefixes lists the line prefixes of each metric, covering both the 0.4 and 1.0 output formats.
+var sysbenchPrefixes = map[string][]string{
+	SysbenchTotalTime:  {"total time:"},
+	SysbenchEvents:     {"total number of events:"},
+	SysbenchLatencyMin: {"min:"},
+	SysbenchLatencyAvg: {"avg:"},
+	SysbenchLatencyMax: {"max:"},
+	SysbenchLatency95:  {"approx.  95 percentile:", "95th percentile:"},
+}
+
+// Sysbench extracts a well-known metric from sysbench output independent of the sysbench version.
+// Extracting an unknown metric fails with ErrUnknownMetric.
+func Sysbench(metric string) Extractor {
+	prefixes, ok := sysbenchPrefixes[metric]
+	if !ok {
+		return ExtractorFunc(func(data []byte) (float64, error) {
+			return 0, fmt.Errorf("%w: sysbench %q", ErrUnknownMetric, metric)
+		})
+	}
+	extractors := make([]Extractor, len(prefixes))
+	for i, prefix := range prefixes {
+		extractors[i] = Prefix(prefix)
+	}
+	return First(extractors...)
+}
@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/lnsp/touchstone/pkg/util"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// Backend is an alternative runtime implementation selectable by its CRI name.
+type Backend struct {
+	// Dial creates a runtime for the backend.
+	Dial func() (Runtime, error)
+	// Handlers lists the supported runtime handlers. The first one is the default.
+	// Backends without handlers accept the configured ones like a CRI endpoint.
+	Handlers []string
+}
+
+var (
+	backendsLock sync.Mutex
+	backends     = make(map[string]Backend)
+)
+
+// Register makes a backend available under the given CRI name.
+func Register(name string, backend Backend) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	if _, ok := backends[name]; ok {
+		panic("runtime: backend " + name + " registered twice")
+	}
+	backends[name] = backend
+}
+
+// LookupBackend returns the backend registered under the given CRI name.
+func LookupBackend(name string) (Backend, bool) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	backend, ok := backends[name]
+	return backend, ok
+}
+
+// Dial connects to the named CRI. Registered backends take precedence over CRI endpoints.
+// It serves as the default factory of the benchmark matrix.
+func Dial(cri string) (Runtime, error) {
+	if backend, ok := LookupBackend(cri); ok {
+		return backend.Dial()
+	}
+	return NewClient(util.GetCRIEndpoint(cri))
+}
+
+// Handlers returns the runtime handlers to evaluate for the CRI.
+// Backends only evaluate the configured handlers they support, falling back to their default handler.
+// CRI endpoints skip the handlers exclusive to backends.
+func Handlers(cri string, ocis []string) []string {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	backend, ok := backends[cri]
+	exclusive := ok && backend.Handlers != nil
+	var handlers []string
+	for _, oci := range ocis {
+		if exclusive && containsString(backend.Handlers, oci) {
+			handlers = append(handlers, oci)
+		}
+		if !exclusive && !isBackendHandler(oci) {
+			handlers = append(handlers, oci)
+		}
+	}
+	if exclusive && len(handlers) == 0 {
+		handlers = backend.Handlers[:1]
+	}
+	return handlers
+}
+
+// HandlerBackend returns the name of the backend providing the handler, the first by name if several do.
+func HandlerBackend(handler string) (string, bool) {
+	backendsLock.Lock()
+	defer backendsLock.Unlock()
+	var names []string
+	for name, backend := range backends {
+		if containsString(backend.Handlers, handler) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+// isBackendHandler checks if any backend provides the handler. The caller must hold backendsLock.
+func isBackendHandler(handler string) bool {
+	for _, backend := range backends {
+		if containsString(backend.Handlers, handler) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// NewLocalClient wraps in-process service implementations into a client.
+// The closer is invoked when the client is closed and may be nil.
+func NewLocalClient(runtime runtimeapi.RuntimeServiceClient, image runtimeapi.ImageServiceClient, closer io.Closer) *Client {
+	return &Client{
+		Runtime: runtime,
+		Image:   image,
+		conn:    closer,
+		api:     APIVersionV1alpha2,
+	}
+}
@@ -0,0 +1,52 @@
+package runtime
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHandlers(t *testing.T) {
+	Register("test-backend", Backend{Handlers: []string{"host", "unshare"}})
+	Register("test-direct", Backend{})
+	defer func() {
+		backendsLock.Lock()
+		delete(backends, "test-backend")
+		delete(backends, "test-direct")
+		backendsLock.Unlock()
+	}()
+	tt := []struct {
+		Name     string
+		CRI      string
+		OCIs     []string
+		Handlers []string
+	}{
+		{"endpoint", "containerd", []string{"runc", "runsc"}, []string{"runc", "runsc"}},
+		{"endpoint skips backend handlers", "containerd", []string{"runc", "unshare"}, []string{"runc"}},
+		{"backend default", "test-backend", []string{"runc", "runsc"}, []string{"host"}},
+		{"backend configured", "test-backend", []string{"runc", "unshare"}, []string{"unshare"}},
+		{"backend without handlers", "test-direct", []string{"runc", "runsc", "unshare"}, []string{"runc", "runsc"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			handlers := Handlers(tc.CRI, tc.OCIs)
+			if !reflect.DeepEqual(handlers, tc.Handlers) {
+				t.Errorf("expected %v, got %v", tc.Handlers, handlers)
+			}
+		})
+	}
+}
+
+func TestHandlerBackend(t *testing.T) {
+	Register("test-backend", Backend{Handlers: []string{"host", "unshare"}})
+	defer func() {
+		backendsLock.Lock()
+		delete(backends, "test-backend")
+		backendsLock.Unlock()
+	}()
+	if name, ok := HandlerBackend("unshare"); !ok || name != "test-backend" {
+		t.Errorf("expected backend test-backend, got %q", name)
+	}
+	if name, ok := HandlerBackend("runc"); ok {
+		t.Errorf("expected no backend for runc, got %q", name)
+	}
+}
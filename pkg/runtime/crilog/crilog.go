@@ -0,0 +1,89 @@
+// Package crilog writes process output in the CRI container logging format.
+package crilog
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File writes the output streams of a process in the CRI logging format.
+type File struct {
+	mu   sync.Mutex
+	file *os.File
+	// streams keeps the incomplete last line of each stream.
+	streams map[string]*Stream
+}
+
+// Stream is a single output stream of a log file.
+type Stream struct {
+	name    string
+	log     *File
+	partial []byte
+}
+
+// Open creates or truncates the log file, creating missing parent directories.
+func Open(path string) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		file:    f,
+		streams: make(map[string]*Stream),
+	}, nil
+}
+
+// Stream returns a writer prefixing each line with a timestamp and the stream name.
+// The writers of a file must not be used concurrently with Close.
+func (l *File) Stream(name string) *Stream {
+	s := &Stream{name: name, log: l}
+	l.streams[name] = s
+	return s
+}
+
+// writeLine writes a single entry. Tag is F for full and P for partial lines.
+func (l *File) writeLine(stream, tag string, line []byte) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	buf := make([]byte, 0, len(line)+64)
+	buf = append(buf, time.Now().UTC().Format(time.RFC3339Nano)...)
+	buf = append(buf, ' ')
+	buf = append(buf, stream...)
+	buf = append(buf, ' ')
+	buf = append(buf, tag...)
+	buf = append(buf, ' ')
+	buf = append(buf, line...)
+	buf = append(buf, '\n')
+	l.file.Write(buf)
+}
+
+func (s *Stream) Write(p []byte) (int, error) {
+	data := append(s.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		s.log.writeLine(s.name, "F", data[:i])
+		data = data[i+1:]
+	}
+	s.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// Close flushes incomplete lines and closes the file.
+func (l *File) Close() error {
+	for _, s := range l.streams {
+		if len(s.partial) > 0 {
+			l.writeLine(s.name, "P", s.partial)
+			s.partial = nil
+		}
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,466 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/pkg/errors"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const logPollInterval = 10 * time.Millisecond
+
+// StartSandbox records the sandbox. Its handler selects the OCI runtime of the containers.
+func (c *Client) StartSandbox(config *runtimeapi.PodSandboxConfig, handler string) (string, error) {
+	sb := &sandbox{
+		id:        runtime.NewUUID(),
+		config:    config,
+		handler:   handler,
+		createdAt: time.Now().UnixNano(),
+		state:     runtimeapi.PodSandboxState_SANDBOX_READY,
+	}
+	c.mu.Lock()
+	c.sandboxes[sb.id] = sb
+	c.mu.Unlock()
+	return sb.id, nil
+}
+
+func (c *Client) lookupSandbox(pod string) (*sandbox, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	sb, ok := c.sandboxes[pod]
+	if !ok {
+		return nil, errors.Errorf("sandbox %s not found", pod)
+	}
+	return sb, nil
+}
+
+func (c *Client) SandboxStatus(pod string) (*runtimeapi.PodSandboxStatus, error) {
+	sb, err := c.lookupSandbox(pod)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &runtimeapi.PodSandboxStatus{
+		Id:             sb.id,
+		Metadata:       sb.config.GetMetadata(),
+		State:          sb.state,
+		CreatedAt:      sb.createdAt,
+		Labels:         sb.config.GetLabels(),
+		Annotations:    sb.config.GetAnnotations(),
+		RuntimeHandler: sb.handler,
+	}, nil
+}
+
+// sandboxContainers lists the IDs of all containers labeled with the sandbox.
+func (c *Client) sandboxContainers(pod string) ([]string, error) {
+	filters, err := json.Marshal(map[string][]string{
+		"label": {sandboxLabel + "=" + pod},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var containers []struct {
+		ID string `json:"Id"`
+	}
+	if err := c.do(http.MethodGet, "/containers/json", url.Values{
+		"all":     {"1"},
+		"filters": {string(filters)},
+	}, nil, &containers); err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(containers))
+	for i, container := range containers {
+		ids[i] = container.ID
+	}
+	return ids, nil
+}
+
+// StopSandbox stops all containers of the sandbox.
+func (c *Client) StopSandbox(pod string) error {
+	sb, err := c.lookupSandbox(pod)
+	if err != nil {
+		return err
+	}
+	containers, err := c.sandboxContainers(pod)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if err := c.StopContainer(container, 0); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	sb.state = runtimeapi.PodSandboxState_SANDBOX_NOTREADY
+	c.mu.Unlock()
+	return nil
+}
+
+// RemoveSandbox removes all containers of the sandbox.
+func (c *Client) RemoveSandbox(pod string) error {
+	containers, err := c.sandboxContainers(pod)
+	if err != nil {
+		return err
+	}
+	for _, container := range containers {
+		if err := c.RemoveContainer(container); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	delete(c.sandboxes, pod)
+	c.mu.Unlock()
+	return nil
+}
+
+// hostConfig holds the engine settings of a container.
+type hostConfig struct {
+	Runtime    string   `json:"Runtime,omitempty"`
+	Binds      []string `json:"Binds,omitempty"`
+	CPUPeriod  int64    `json:"CpuPeriod,omitempty"`
+	CPUQuota   int64    `json:"CpuQuota,omitempty"`
+	CPUShares  int64    `json:"CpuShares,omitempty"`
+	CpusetCpus string   `json:"CpusetCpus,omitempty"`
+	CpusetMems string   `json:"CpusetMems,omitempty"`
+	Memory     int64    `json:"Memory,omitempty"`
+}
+
+// withResources copies the CRI resource limits into the host config.
+func (h hostConfig) withResources(r *runtimeapi.LinuxContainerResources) hostConfig {
+	if r == nil {
+		return h
+	}
+	h.CPUPeriod = r.CpuPeriod
+	h.CPUQuota = r.CpuQuota
+	h.CPUShares = r.CpuShares
+	h.CpusetCpus = r.CpusetCpus
+	h.CpusetMems = r.CpusetMems
+	h.Memory = r.MemoryLimitInBytes
+	return h
+}
+
+// CreateContainerWithOptions creates a container running the command as entrypoint.
+func (c *Client) CreateContainerWithOptions(config *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, opts runtime.ContainerOptions) (string, error) {
+	sb, err := c.lookupSandbox(pod)
+	if err != nil {
+		return "", err
+	}
+	host := hostConfig{Runtime: sb.handler}.withResources(opts.Resources)
+	for _, m := range opts.Mounts {
+		bind := m.HostPath + ":" + m.ContainerPath
+		if m.Readonly {
+			bind += ":ro"
+		}
+		host.Binds = append(host.Binds, bind)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	// Engine container names are global, qualify them with the sandbox like the CRI does
+	if err := c.do(http.MethodPost, "/containers/create", url.Values{"name": {name + "_" + pod}}, map[string]interface{}{
+		"Image":      image,
+		"Entrypoint": command,
+		"WorkingDir": opts.WorkingDir,
+		"Labels":     map[string]string{sandboxLabel: pod},
+		"HostConfig": host,
+	}, &created); err != nil {
+		return "", err
+	}
+	return created.ID, nil
+}
+
+func (c *Client) StartContainer(container string) error {
+	return c.do(http.MethodPost, "/containers/"+container+"/start", nil, nil, nil)
+}
+
+func (c *Client) StopContainer(container string, timeout int) error {
+	return c.do(http.MethodPost, "/containers/"+container+"/stop", url.Values{
+		"t": {strconv.Itoa(timeout)},
+	}, nil, nil)
+}
+
+func (c *Client) RemoveContainer(container string) error {
+	return c.do(http.MethodDelete, "/containers/"+container, url.Values{"force": {"1"}}, nil, nil)
+}
+
+// inspect is the subset of the container inspection used for the status.
+type inspect struct {
+	ID      string `json:"Id"`
+	Name    string
+	Created string
+	Image   string
+	Config  struct {
+		Image  string
+		Labels map[string]string
+	}
+	State struct {
+		Status     string
+		ExitCode   int32
+		OOMKilled  bool
+		Error      string
+		StartedAt  string
+		FinishedAt string
+	}
+}
+
+func (c *Client) Status(container string) (*runtimeapi.ContainerStatus, error) {
+	var info inspect
+	if err := c.do(http.MethodGet, "/containers/"+container+"/json", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return info.status(), nil
+}
+
+func (info *inspect) status() *runtimeapi.ContainerStatus {
+	status := &runtimeapi.ContainerStatus{
+		Id: info.ID,
+		Metadata: &runtimeapi.ContainerMetadata{
+			Name: strings.TrimSuffix(strings.TrimPrefix(info.Name, "/"), "_"+info.Config.Labels[sandboxLabel]),
+		},
+		CreatedAt:  unixNano(info.Created),
+		StartedAt:  unixNano(info.State.StartedAt),
+		FinishedAt: unixNano(info.State.FinishedAt),
+		ExitCode:   info.State.ExitCode,
+		Image:      &runtimeapi.ImageSpec{Image: info.Config.Image},
+		ImageRef:   info.Image,
+		Message:    info.State.Error,
+		Labels:     info.Config.Labels,
+	}
+	switch info.State.Status {
+	case "created":
+		status.State = runtimeapi.ContainerState_CONTAINER_CREATED
+	case "running", "paused", "restarting":
+		status.State = runtimeapi.ContainerState_CONTAINER_RUNNING
+	case "exited", "dead":
+		status.State = runtimeapi.ContainerState_CONTAINER_EXITED
+	default:
+		status.State = runtimeapi.ContainerState_CONTAINER_UNKNOWN
+	}
+	if status.State == runtimeapi.ContainerState_CONTAINER_EXITED {
+		switch {
+		case info.State.OOMKilled:
+			status.Reason = "OOMKilled"
+		case info.State.ExitCode != 0:
+			status.Reason = "Error"
+		default:
+			status.Reason = "Completed"
+		}
+	}
+	return status
+}
+
+// unixNano converts an engine timestamp. Unset timestamps are reported as zero.
+func unixNano(timestamp string) int64 {
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil || t.Year() <= 1 {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func (c *Client) UpdateContainerResources(container string, resources *runtimeapi.LinuxContainerResources) error {
+	return c.do(http.MethodPost, "/containers/"+container+"/update", nil, hostConfig{}.withResources(resources), nil)
+}
+
+// PullImage pulls the image and waits for the progress stream to finish.
+func (c *Client) PullImage(image string, sandbox *runtimeapi.PodSandboxConfig) error {
+	name, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+	resp, err := c.request(http.MethodPost, "/images/create", url.Values{
+		"fromImage": {name},
+		"tag":       {tag},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if progress.Error != "" {
+			return errors.Errorf("failed to pull %s: %s", image, progress.Error)
+		}
+	}
+}
+
+// ExecSync runs the command in the container and collects its output.
+func (c *Client) ExecSync(container string, cmd []string, timeout time.Duration) (*runtimeapi.ExecSyncResponse, error) {
+	var exec struct {
+		ID string `json:"Id"`
+	}
+	if err := c.do(http.MethodPost, "/containers/"+container+"/exec", nil, map[string]interface{}{
+		"Cmd":          cmd,
+		"AttachStdout": true,
+		"AttachStderr": true,
+	}, &exec); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	data, err := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, "http://"+c.name+"/"+apiVersion+"/exec/"+exec.ID+"/start", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := demux(resp.Body, stdout, stderr); err != nil {
+		return nil, err
+	}
+	var result struct {
+		ExitCode int32
+	}
+	if err := c.do(http.MethodGet, "/exec/"+exec.ID+"/json", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &runtimeapi.ExecSyncResponse{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: result.ExitCode,
+	}, nil
+}
+
+// logs fetches the timestamped output streams of the container.
+func (c *Client) logs(container string, stdout, stderr io.Writer) error {
+	resp, err := c.request(http.MethodGet, "/containers/"+container+"/logs", url.Values{
+		"stdout":     {"1"},
+		"stderr":     {"1"},
+		"timestamps": {"1"},
+	}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return demux(resp.Body, stdout, stderr)
+}
+
+// LogStreams fetches the logs of the container and strips the timestamps.
+func (c *Client) LogStreams(container string, stdout, stderr io.Writer) error {
+	outBuf, errBuf := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := c.logs(container, outBuf, errBuf); err != nil {
+		return err
+	}
+	if err := stripTimestamps(stdout, outBuf); err != nil {
+		return err
+	}
+	return stripTimestamps(stderr, errBuf)
+}
+
+// stripTimestamps copies the log lines without their leading timestamp.
+func stripTimestamps(w io.Writer, r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			line = line[i+1:]
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// FirstLogTime polls the logs until the container printed its first line.
+func (c *Client) FirstLogTime(container string, timeout time.Duration) (time.Time, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		buf := &bytes.Buffer{}
+		if err := c.logs(container, buf, buf); err != nil {
+			return time.Time{}, err
+		}
+		if line, err := buf.ReadString(' '); err == nil {
+			return time.Parse(time.RFC3339Nano, strings.TrimSpace(line))
+		}
+		if time.Now().After(deadline) {
+			return time.Time{}, errors.Errorf("no logs from container %s after %v", container, timeout)
+		}
+		<-time.After(logPollInterval)
+	}
+}
+
+// ContainerStats fetches a single sample of the CPU and memory usage.
+func (c *Client) ContainerStats(container string) (*runtimeapi.ContainerStats, error) {
+	var stats struct {
+		Read     string
+		CPUStats struct {
+			CPUUsage struct {
+				TotalUsage uint64 `json:"total_usage"`
+			} `json:"cpu_usage"`
+		} `json:"cpu_stats"`
+		MemoryStats struct {
+			Usage uint64 `json:"usage"`
+		} `json:"memory_stats"`
+	}
+	if err := c.do(http.MethodGet, "/containers/"+container+"/stats", url.Values{"stream": {"0"}}, nil, &stats); err != nil {
+		return nil, err
+	}
+	timestamp := unixNano(stats.Read)
+	return &runtimeapi.ContainerStats{
+		Attributes: &runtimeapi.ContainerAttributes{Id: container},
+		Cpu: &runtimeapi.CpuUsage{
+			Timestamp:            timestamp,
+			UsageCoreNanoSeconds: &runtimeapi.UInt64Value{Value: stats.CPUStats.CPUUsage.TotalUsage},
+		},
+		Memory: &runtimeapi.MemoryUsage{
+			Timestamp:       timestamp,
+			WorkingSetBytes: &runtimeapi.UInt64Value{Value: stats.MemoryStats.Usage},
+		},
+	}, nil
+}
+
+// demux splits a multiplexed output stream. Each frame starts with an 8 byte header
+// holding the stream type and the big endian payload size.
+func demux(r io.Reader, stdout, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		size := int64(header[4])<<24 | int64(header[5])<<16 | int64(header[6])<<8 | int64(header[7])
+		w := stdout
+		if header[0] == 2 {
+			w = stderr
+		}
+		if _, err := io.CopyN(w, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+var _ runtime.Runtime = &Client{}
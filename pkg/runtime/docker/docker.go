@@ -0,0 +1,216 @@
+// Package docker implements the runtime on top of the Docker Engine API.
+// Podman serves the same API through its compatibility socket.
+//
+// The engines have no notion of pod sandboxes, sandboxes are tracked by touchstone
+// and their containers are labeled with the sandbox ID.
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/pkg/errors"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const (
+	// Docker is the CRI name of the Docker Engine backend.
+	Docker = "docker"
+	// Podman is the CRI name of the Podman backend.
+	Podman = "podman"
+)
+
+const (
+	dockerSocket = "/var/run/docker.sock"
+	podmanSocket = "/run/podman/podman.sock"
+	// apiVersion is the lowest Docker Engine API version served by both engines.
+	apiVersion = "v1.40"
+	// sandboxLabel marks the containers created in a sandbox.
+	sandboxLabel = "io.touchstone.sandbox"
+)
+
+func init() {
+	runtime.Register(Docker, runtime.Backend{
+		Dial: func() (runtime.Runtime, error) {
+			return Dial(Docker, socket("DOCKER_HOST", dockerSocket))
+		},
+	})
+	runtime.Register(Podman, runtime.Backend{
+		Dial: func() (runtime.Runtime, error) {
+			return Dial(Podman, socket("PODMAN_HOST", podmanSocket))
+		},
+	})
+}
+
+// socket returns the unix socket configured in the environment variable or the default.
+func socket(env, def string) string {
+	if host := os.Getenv(env); strings.HasPrefix(host, "unix://") {
+		return strings.TrimPrefix(host, "unix://")
+	}
+	return def
+}
+
+// Client is a runtime backed by the Docker Engine API.
+type Client struct {
+	name string
+	http *http.Client
+
+	mu        sync.Mutex
+	sandboxes map[string]*sandbox
+}
+
+type sandbox struct {
+	id        string
+	config    *runtimeapi.PodSandboxConfig
+	handler   string
+	createdAt int64
+	state     runtimeapi.PodSandboxState
+}
+
+// Dial connects to the engine listening on the unix socket and verifies that it responds.
+func Dial(name, socket string) (*Client, error) {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		},
+	}
+	client := NewClient(name, &http.Client{Transport: transport})
+	if err := client.do(http.MethodGet, "/_ping", nil, nil, nil); err != nil {
+		return nil, errors.Wrapf(err, "failed to reach %s at %s", name, socket)
+	}
+	return client, nil
+}
+
+// NewClient creates a runtime sending its requests through the HTTP client.
+func NewClient(name string, client *http.Client) *Client {
+	return &Client{
+		name:      name,
+		http:      client,
+		sandboxes: make(map[string]*sandbox),
+	}
+}
+
+// apiError is the error body returned by the engine.
+type apiError struct {
+	Message string `json:"message"`
+}
+
+// request performs an API call and returns the response if it succeeded.
+func (c *Client) request(method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	u := "http://" + c.name + "/" + apiVersion + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var apiErr apiError
+		data, _ := ioutil.ReadAll(resp.Body)
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, apiErr.Message)
+	}
+	return resp, nil
+}
+
+// do performs an API call and decodes the JSON response into out, if given.
+func (c *Client) do(method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.request(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, err := io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) VersionInfo() (*runtimeapi.VersionResponse, error) {
+	var version struct {
+		Version    string
+		APIVersion string `json:"ApiVersion"`
+	}
+	if err := c.do(http.MethodGet, "/version", nil, nil, &version); err != nil {
+		return nil, err
+	}
+	return &runtimeapi.VersionResponse{
+		Version:           version.APIVersion,
+		RuntimeName:       c.name,
+		RuntimeVersion:    version.Version,
+		RuntimeApiVersion: version.APIVersion,
+	}, nil
+}
+
+// RuntimeStatus reports the engine as ready if it answers pings.
+// Verbose requests include the configured OCI runtimes and storage settings.
+func (c *Client) RuntimeStatus(verbose bool) (*runtimeapi.StatusResponse, error) {
+	if err := c.do(http.MethodGet, "/_ping", nil, nil, nil); err != nil {
+		return nil, err
+	}
+	resp := &runtimeapi.StatusResponse{
+		Status: &runtimeapi.RuntimeStatus{
+			Conditions: []*runtimeapi.RuntimeCondition{
+				{Type: runtimeapi.RuntimeReady, Status: true},
+				{Type: runtimeapi.NetworkReady, Status: true},
+			},
+		},
+	}
+	if !verbose {
+		return resp, nil
+	}
+	var info struct {
+		Runtimes       json.RawMessage
+		DefaultRuntime string
+		Driver         string
+		CgroupDriver   string
+		CgroupVersion  string
+	}
+	if err := c.do(http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return nil, err
+	}
+	resp.Info = map[string]string{
+		"runtimes":       string(info.Runtimes),
+		"defaultRuntime": info.DefaultRuntime,
+		"storageDriver":  info.Driver,
+		"cgroupDriver":   info.CgroupDriver,
+		"cgroupVersion":  info.CgroupVersion,
+	}
+	return resp, nil
+}
+
+func (c *Client) Close() {
+	c.http.CloseIdleConnections()
+}
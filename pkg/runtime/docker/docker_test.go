@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDemux(t *testing.T) {
+	var stream []byte
+	stream = append(stream, frame(1, "hello\n")...)
+	stream = append(stream, frame(2, "oops\n")...)
+	stream = append(stream, frame(1, "world\n")...)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := demux(bytes.NewReader(stream), stdout, stderr); err != nil {
+		t.Fatalf("demux failed: %v", err)
+	}
+	if stdout.String() != "hello\nworld\n" {
+		t.Errorf("expected stdout %q, got %q", "hello\nworld\n", stdout.String())
+	}
+	if stderr.String() != "oops\n" {
+		t.Errorf("expected stderr %q, got %q", "oops\n", stderr.String())
+	}
+	if err := demux(bytes.NewReader(frame(1, "truncated")[:10]), stdout, stderr); err == nil {
+		t.Errorf("expected error on truncated stream")
+	}
+}
+
+// newTestClient creates a client talking to the handler.
+func newTestClient(handler http.Handler) (*Client, func()) {
+	server := httptest.NewServer(handler)
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "tcp", server.Listener.Addr().String())
+		},
+	}
+	return NewClient(Docker, &http.Client{Transport: transport}), server.Close
+}
+
+func TestClient(t *testing.T) {
+	var created map[string]interface{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1.40/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("name") == "" {
+			http.Error(w, `{"message":"missing name"}`, http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&created)
+		w.Write([]byte(`{"Id":"abc"}`))
+	})
+	mux.HandleFunc("/v1.40/containers/abc/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{
+			"Id": "abc",
+			"Name": "/bench_pod",
+			"Created": "2020-01-01T00:00:00.5Z",
+			"Config": {"Image": "busybox", "Labels": {"io.touchstone.sandbox": "pod"}},
+			"State": {
+				"Status": "exited",
+				"ExitCode": 137,
+				"OOMKilled": true,
+				"StartedAt": "2020-01-01T00:00:01Z",
+				"FinishedAt": "0001-01-01T00:00:00Z"
+			}
+		}`))
+	})
+	mux.HandleFunc("/v1.40/containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message":"No such container: missing"}`, http.StatusNotFound)
+	})
+	client, stop := newTestClient(mux)
+	defer stop()
+
+	opts := runtime.ContainerOptions{
+		Resources: &runtimeapi.LinuxContainerResources{CpuPeriod: 100000, CpuQuota: 50000},
+	}
+	pod, err := client.StartSandbox(&runtimeapi.PodSandboxConfig{}, "runsc")
+	if err != nil {
+		t.Fatalf("could not start sandbox: %v", err)
+	}
+	id, err := client.CreateContainerWithOptions(nil, pod, "bench", "busybox", []string{"true"}, opts)
+	if err != nil {
+		t.Fatalf("could not create container: %v", err)
+	}
+	if id != "abc" {
+		t.Errorf("expected container ID abc, got %s", id)
+	}
+	host := created["HostConfig"].(map[string]interface{})
+	if host["Runtime"] != "runsc" {
+		t.Errorf("expected runtime runsc, got %v", host["Runtime"])
+	}
+	if host["CpuQuota"] != float64(50000) {
+		t.Errorf("expected cpu quota 50000, got %v", host["CpuQuota"])
+	}
+	if _, err := client.CreateContainerWithOptions(nil, "unknown", "bench", "busybox", nil, opts); err == nil {
+		t.Errorf("expected error for unknown sandbox")
+	}
+
+	status, err := client.Status("abc")
+	if err != nil {
+		t.Fatalf("could not fetch status: %v", err)
+	}
+	if status.State != runtimeapi.ContainerState_CONTAINER_EXITED {
+		t.Errorf("expected state exited, got %v", status.State)
+	}
+	if status.Reason != "OOMKilled" || status.ExitCode != 137 {
+		t.Errorf("expected OOMKilled with 137, got %s with %d", status.Reason, status.ExitCode)
+	}
+	if status.Metadata.Name != "bench" {
+		t.Errorf("expected name bench, got %s", status.Metadata.Name)
+	}
+	if status.CreatedAt != 1577836800500000000 || status.FinishedAt != 0 {
+		t.Errorf("unexpected timestamps created=%d finished=%d", status.CreatedAt, status.FinishedAt)
+	}
+	if _, err := client.Status("missing"); err == nil {
+		t.Errorf("expected error for missing container")
+	}
+}
@@ -0,0 +1,74 @@
+package runtime
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const maxLogTailLines = 20
+
+const reasonOOMKilled = "OOMKilled"
+
+// ContainerError describes a container that did not exit successfully,
+// or exited successfully before it was expected to stop.
+type ContainerError struct {
+	Container string
+	ExitCode  int32
+	Reason    string
+	Message   string
+	Stderr    []byte
+	Tail      []byte
+}
+
+func (e *ContainerError) Error() string {
+	msg := fmt.Sprintf("container %s failed with exit code %d", e.Container, e.ExitCode)
+	if e.ExitCode == 0 && e.Reason != reasonOOMKilled {
+		msg = fmt.Sprintf("container %s exited with exit code 0 before it was expected to stop", e.Container)
+	}
+	if e.Reason != "" {
+		msg += fmt.Sprintf(" (%s)", e.Reason)
+	}
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	if stderr := strings.TrimSpace(string(tail(e.Stderr, 1))); stderr != "" {
+		msg += fmt.Sprintf(", stderr: %s", stderr)
+	}
+	return msg
+}
+
+// Log returns the last lines logged by the container.
+func (e *ContainerError) Log() []byte {
+	return e.Tail
+}
+
+// CheckExit verifies that the container exited with a zero exit code and was not OOM killed.
+func CheckExit(status *runtimeapi.ContainerStatus, logs, stderr []byte) error {
+	if status.ExitCode == 0 && status.Reason != reasonOOMKilled {
+		return nil
+	}
+	return newContainerError(status, logs, stderr)
+}
+
+func newContainerError(status *runtimeapi.ContainerStatus, logs, stderr []byte) *ContainerError {
+	return &ContainerError{
+		Container: status.Id,
+		ExitCode:  status.ExitCode,
+		Reason:    status.Reason,
+		Message:   status.Message,
+		Stderr:    stderr,
+		Tail:      tail(logs, maxLogTailLines),
+	}
+}
+
+// tail returns the last n lines of the logs.
+func tail(logs []byte, n int) []byte {
+	lines := bytes.Split(bytes.TrimRight(logs, "\n"), []byte("\n"))
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestCheckExit(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Status *runtimeapi.ContainerStatus
+		Failed bool
+	}{
+		{"success", &runtimeapi.ContainerStatus{Id: "a", ExitCode: 0}, false},
+		{"exit-code", &runtimeapi.ContainerStatus{Id: "b", ExitCode: 1}, true},
+		{"oom-killed", &runtimeapi.ContainerStatus{Id: "c", ExitCode: 137, Reason: "OOMKilled"}, true},
+	}
+	logs := []byte("line 1\nline 2\n")
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := CheckExit(tc.Status, logs, nil)
+			if !tc.Failed {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			var containerErr *ContainerError
+			if !errors.As(err, &containerErr) {
+				t.Fatalf("expected container error, got %v", err)
+			}
+			if containerErr.ExitCode != tc.Status.ExitCode || containerErr.Reason != tc.Status.Reason {
+				t.Errorf("expected status %v, got %v", tc.Status, containerErr)
+			}
+			if string(containerErr.Log()) != "line 1\nline 2" {
+				t.Errorf("expected log tail, got %q", containerErr.Log())
+			}
+		})
+	}
+}
+
+// fakeStatusRuntime reports a fixed container status and logs.
+type fakeStatusRuntime struct {
+	Runtime
+	status *runtimeapi.ContainerStatus
+}
+
+func (f *fakeStatusRuntime) Status(container string) (*runtimeapi.ContainerStatus, error) {
+	return f.status, nil
+}
+
+func (f *fakeStatusRuntime) LogStreams(container string, stdout, stderr io.Writer) error {
+	_, err := io.WriteString(stdout, "done\n")
+	return err
+}
+
+func TestCheckRunning(t *testing.T) {
+	tt := []struct {
+		Name   string
+		Status *runtimeapi.ContainerStatus
+		Error  string
+	}{
+		{"running", &runtimeapi.ContainerStatus{Id: "a", State: runtimeapi.ContainerState_CONTAINER_RUNNING}, ""},
+		{"exited-successfully", &runtimeapi.ContainerStatus{Id: "b", State: runtimeapi.ContainerState_CONTAINER_EXITED}, "container b exited with exit code 0 before it was expected to stop"},
+		{"exited-failed", &runtimeapi.ContainerStatus{Id: "c", State: runtimeapi.ContainerState_CONTAINER_EXITED, ExitCode: 2}, "container c failed with exit code 2"},
+		{"created", &runtimeapi.ContainerStatus{Id: "d", State: runtimeapi.ContainerState_CONTAINER_CREATED}, "container d is not running, state CONTAINER_CREATED"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := CheckRunning(&fakeStatusRuntime{status: tc.Status}, tc.Status.Id)
+			if tc.Error == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.Error {
+				t.Errorf("expected error %q, got %v", tc.Error, err)
+			}
+		})
+	}
+}
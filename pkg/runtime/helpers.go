@@ -0,0 +1,148 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// CreateContainer runs a container image. It returns the container ID.
+func CreateContainer(rt Runtime, sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string) (string, error) {
+	return CreateContainerWithResources(rt, sandbox, pod, name, image, command, nil)
+}
+
+// CreateContainerWithResources runs a container image with resource limits. It returns the container ID.
+func CreateContainerWithResources(rt Runtime, sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, resources *runtimeapi.LinuxContainerResources) (string, error) {
+	return rt.CreateContainerWithOptions(sandbox, pod, name, image, command, ContainerOptions{
+		Resources: resources,
+	})
+}
+
+// WaitForExit waits for the container to exit and returns its final status.
+func WaitForExit(rt Runtime, container string) (*runtimeapi.ContainerStatus, error) {
+	for {
+		status, err := rt.Status(container)
+		if err != nil {
+			return nil, err
+		}
+		if status.State >= 2 {
+			return status, nil
+		}
+		<-time.After(time.Second)
+	}
+}
+
+// WaitForLogs waits for the container to exit and returns the logs as a slice of bytes.
+// It fails with a *ContainerError if the container did not exit successfully.
+func WaitForLogs(rt Runtime, container string) ([]byte, error) {
+	status, err := WaitForExit(rt, container)
+	if err != nil {
+		return nil, err
+	}
+	buf, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := rt.LogStreams(container, buf, io.MultiWriter(buf, stderr)); err != nil {
+		return nil, err
+	}
+	if err := CheckExit(status, buf.Bytes(), stderr.Bytes()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// CheckRunning verifies that the container has not terminated yet.
+// It fails with a *ContainerError if the container already exited, even if it exited successfully.
+func CheckRunning(rt Runtime, container string) error {
+	status, err := rt.Status(container)
+	if err != nil {
+		return err
+	}
+	switch status.State {
+	case runtimeapi.ContainerState_CONTAINER_RUNNING:
+		return nil
+	case runtimeapi.ContainerState_CONTAINER_EXITED:
+	default:
+		return errors.Errorf("container %s is not running, state %s", container, status.State)
+	}
+	buf, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := rt.LogStreams(container, buf, io.MultiWriter(buf, stderr)); err != nil {
+		return err
+	}
+	return newContainerError(status, buf.Bytes(), stderr.Bytes())
+}
+
+// Logs fetches the logs of the container.
+func Logs(rt Runtime, container string, writer io.Writer) error {
+	return rt.LogStreams(container, writer, writer)
+}
+
+// State fetches the state of the container.
+func State(rt Runtime, container string) (runtimeapi.ContainerState, error) {
+	status, err := rt.Status(container)
+	if err != nil {
+		return 0, err
+	}
+	return status.State, nil
+}
+
+// RunSandbox initializes and starts a new pod sandbox with the given runtime handler.
+func RunSandbox(rt Runtime, name, handler string) (*Sandbox, error) {
+	config := InitLinuxSandbox(name)
+	pod, err := rt.StartSandbox(config, handler)
+	if err != nil {
+		return nil, err
+	}
+	return &Sandbox{
+		ID:      pod,
+		Handler: handler,
+		Config:  config,
+	}, nil
+}
+
+// StopAndRemoveContainer stops and removes a container.
+func StopAndRemoveContainer(rt Runtime, container string) (err error) {
+	for attempt := 0; attempt < maxRemovalAttempts; attempt++ {
+		err = rt.StopContainer(container, maxRemovalTimeout)
+		if err != nil {
+			continue
+		}
+		err = rt.RemoveContainer(container)
+		if err != nil {
+			continue
+		}
+		return nil
+	}
+	return errors.Errorf("stop-remove container failed: %v", err)
+}
+
+// StopAndRemoveSandbox stops and removes the given pod sandbox.
+func StopAndRemoveSandbox(rt Runtime, pod string) (err error) {
+	for attempt := 0; attempt < maxRemovalAttempts; attempt++ {
+		err = rt.StopSandbox(pod)
+		if err != nil {
+			continue
+		}
+		err = rt.RemoveSandbox(pod)
+		if err != nil {
+			continue
+		}
+		return nil
+	}
+	return errors.Errorf("stop-remove pod failed: %v", err)
+}
+
+// InitLinuxSandbox creates a new pod sandbox configuration.
+func InitLinuxSandbox(name string) *runtimeapi.PodSandboxConfig {
+	return &runtimeapi.PodSandboxConfig{
+		Metadata: &runtimeapi.PodSandboxMetadata{
+			Name:      name,
+			Uid:       NewUUID(),
+			Namespace: defaultNamespace,
+			Attempt:   1,
+		},
+		Linux:  &runtimeapi.LinuxPodSandboxConfig{},
+		Labels: defaultLinuxPodLabels,
+	}
+}
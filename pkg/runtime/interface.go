@@ -0,0 +1,58 @@
+package runtime
+
+import (
+	"io"
+	"time"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// Runtime is the set of container runtime operations benchmarks depend on.
+// The CRI types serve as common data model, Client implements it on top of a CRI endpoint.
+type Runtime interface {
+	// VersionInfo fetches the name and version of the runtime.
+	VersionInfo() (*runtimeapi.VersionResponse, error)
+	// RuntimeStatus fetches the runtime conditions.
+	RuntimeStatus(verbose bool) (*runtimeapi.StatusResponse, error)
+
+	// StartSandbox starts up the pod sandbox. It returns the pod sandbox ID.
+	StartSandbox(sandbox *runtimeapi.PodSandboxConfig, handler string) (string, error)
+	// SandboxStatus fetches the status of a pod sandbox.
+	SandboxStatus(pod string) (*runtimeapi.PodSandboxStatus, error)
+	// StopSandbox stops all containers of the pod sandbox.
+	StopSandbox(pod string) error
+	// RemoveSandbox removes the pod sandbox.
+	RemoveSandbox(pod string) error
+
+	// CreateContainerWithOptions creates a container in the pod sandbox. It returns the container ID.
+	CreateContainerWithOptions(sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, opts ContainerOptions) (string, error)
+	// StartContainer starts a created container.
+	StartContainer(container string) error
+	// StopContainer stops the container, killing it after the timeout in seconds.
+	StopContainer(container string, timeout int) error
+	// RemoveContainer removes a stopped container.
+	RemoveContainer(container string) error
+	// Status fetches the status of a container.
+	Status(container string) (*runtimeapi.ContainerStatus, error)
+	// UpdateContainerResources updates the Linux resources of a container.
+	UpdateContainerResources(container string, resources *runtimeapi.LinuxContainerResources) error
+
+	// PullImage makes the image available to the runtime.
+	PullImage(image string, sandbox *runtimeapi.PodSandboxConfig) error
+
+	// ExecSync runs a command in the container and waits for it to finish.
+	ExecSync(container string, cmd []string, timeout time.Duration) (*runtimeapi.ExecSyncResponse, error)
+
+	// LogStreams fetches the logs of the container and splits them into stdout and stderr.
+	LogStreams(container string, stdout, stderr io.Writer) error
+	// FirstLogTime waits for the first line logged by the container and returns its timestamp.
+	FirstLogTime(container string, timeout time.Duration) (time.Time, error)
+
+	// ContainerStats fetches the resource usage of a container.
+	ContainerStats(container string) (*runtimeapi.ContainerStats, error)
+
+	// Close releases the connection to the runtime.
+	Close()
+}
+
+var _ Runtime = &Client{}
@@ -0,0 +1,217 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime/crilog"
+	"github.com/pkg/errors"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+var (
+	errAlreadyStarted = errors.New("container already started")
+	errEmptyCommand   = errors.New("empty command")
+)
+
+// container is a workload running as a host process.
+type container struct {
+	id      string
+	pod     string
+	handler string
+	dir     string
+	config  *runtimeapi.ContainerConfig
+
+	mu         sync.Mutex
+	cmd        *exec.Cmd
+	log        *crilog.File
+	state      runtimeapi.ContainerState
+	createdAt  int64
+	startedAt  int64
+	finishedAt int64
+	exitCode   int32
+	reason     string
+	message    string
+	done       chan struct{}
+}
+
+func newContainer(id, pod, handler, dir string, config *runtimeapi.ContainerConfig) *container {
+	return &container{
+		id:        id,
+		pod:       pod,
+		handler:   handler,
+		dir:       dir,
+		config:    config,
+		state:     runtimeapi.ContainerState_CONTAINER_CREATED,
+		createdAt: time.Now().UnixNano(),
+		done:      make(chan struct{}),
+	}
+}
+
+// command builds the host command of the container.
+func (c *container) command(argv []string) *exec.Cmd {
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = c.workingDir()
+	cmd.Env = os.Environ()
+	for _, kv := range c.config.Envs {
+		cmd.Env = append(cmd.Env, kv.Key+"="+kv.Value)
+	}
+	cmd.SysProcAttr = sysProcAttr(c.handler)
+	return cmd
+}
+
+// workingDir maps the configured working directory onto the host using the container mounts.
+// Workloads without a working directory run in the container directory.
+func (c *container) workingDir() string {
+	wd := c.config.WorkingDir
+	if wd == "" {
+		return c.dir
+	}
+	for _, m := range c.config.Mounts {
+		if wd == m.ContainerPath || strings.HasPrefix(wd, m.ContainerPath+"/") {
+			return filepath.Join(m.HostPath, strings.TrimPrefix(wd, m.ContainerPath))
+		}
+	}
+	return filepath.Join(c.dir, wd)
+}
+
+func (c *container) start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != runtimeapi.ContainerState_CONTAINER_CREATED {
+		return errAlreadyStarted
+	}
+	log, err := crilog.Open(c.config.LogPath)
+	if err != nil {
+		return err
+	}
+	cmd := c.command(append(append([]string{}, c.config.Command...), c.config.Args...))
+	cmd.Stdout = log.Stream("stdout")
+	cmd.Stderr = log.Stream("stderr")
+	if err := cmd.Start(); err != nil {
+		log.Close()
+		return err
+	}
+	c.cmd, c.log = cmd, log
+	c.state = runtimeapi.ContainerState_CONTAINER_RUNNING
+	c.startedAt = time.Now().UnixNano()
+	go c.wait()
+	return nil
+}
+
+// wait records the exit of the process.
+func (c *container) wait() {
+	err := c.cmd.Wait()
+	c.log.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finishedAt = time.Now().UnixNano()
+	c.state = runtimeapi.ContainerState_CONTAINER_EXITED
+	c.exitCode, c.reason = exitStatus(err)
+	if err != nil && c.exitCode < 0 {
+		c.message = err.Error()
+	}
+	close(c.done)
+}
+
+// exitStatus converts the result of a process into a CRI exit code and reason.
+func exitStatus(err error) (int32, string) {
+	if err == nil {
+		return 0, "Completed"
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			return 128 + int32(ws.Signal()), "Error"
+		}
+		return int32(exitErr.ExitCode()), "Error"
+	}
+	return -1, "Error"
+}
+
+// stop terminates the process, killing it after the timeout.
+func (c *container) stop(timeout time.Duration) {
+	c.mu.Lock()
+	running := c.state == runtimeapi.ContainerState_CONTAINER_RUNNING
+	c.mu.Unlock()
+	if !running {
+		return
+	}
+	if timeout > 0 {
+		c.signal(syscall.SIGTERM)
+		select {
+		case <-c.done:
+			return
+		case <-time.After(timeout):
+		}
+	}
+	c.signal(syscall.SIGKILL)
+	<-c.done
+}
+
+// signal sends the signal to the process group of the container.
+func (c *container) signal(sig syscall.Signal) {
+	syscall.Kill(-c.cmd.Process.Pid, sig)
+}
+
+// exec runs a command next to the container process and captures its output.
+func (c *container) exec(ctx context.Context, argv []string, timeout time.Duration) (*runtimeapi.ExecSyncResponse, error) {
+	if len(argv) == 0 {
+		return nil, errEmptyCommand
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := c.command(argv)
+	cmd.SysProcAttr = sysProcAttr(HandlerHost)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		case <-done:
+		}
+	}()
+	exitCode, _ := exitStatus(cmd.Wait())
+	close(done)
+	return &runtimeapi.ExecSyncResponse{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		ExitCode: exitCode,
+	}, nil
+}
+
+func (c *container) status() *runtimeapi.ContainerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &runtimeapi.ContainerStatus{
+		Id:          c.id,
+		Metadata:    c.config.Metadata,
+		State:       c.state,
+		CreatedAt:   c.createdAt,
+		StartedAt:   c.startedAt,
+		FinishedAt:  c.finishedAt,
+		ExitCode:    c.exitCode,
+		Image:       c.config.Image,
+		ImageRef:    c.config.GetImage().GetImage(),
+		Reason:      c.reason,
+		Message:     c.message,
+		Labels:      c.config.Labels,
+		Annotations: c.config.Annotations,
+		Mounts:      c.config.Mounts,
+		LogPath:     c.config.LogPath,
+	}
+}
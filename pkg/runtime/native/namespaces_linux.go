@@ -0,0 +1,12 @@
+package native
+
+import "syscall"
+
+// sysProcAttr places the process into its own process group and, for the unshare handler, into fresh namespaces.
+func sysProcAttr(handler string) *syscall.SysProcAttr {
+	attr := &syscall.SysProcAttr{Setpgid: true}
+	if handler == HandlerUnshare {
+		attr.Cloneflags = syscall.CLONE_NEWNS | syscall.CLONE_NEWUTS | syscall.CLONE_NEWIPC | syscall.CLONE_NEWPID
+	}
+	return attr
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package native
+
+import "syscall"
+
+// sysProcAttr places the process into its own process group. Namespaces are only supported on Linux.
+func sysProcAttr(handler string) *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setpgid: true}
+}
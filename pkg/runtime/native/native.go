@@ -0,0 +1,409 @@
+// Package native runs container workloads directly as host processes.
+// It provides the bare-metal baseline to compare container runtimes against.
+package native
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// Name is the CRI name of the native backend.
+const Name = "native"
+
+const (
+	// HandlerHost runs workloads as plain host processes.
+	HandlerHost = "host"
+	// HandlerUnshare runs workloads in fresh mount, UTS, IPC and PID namespaces.
+	HandlerUnshare = "unshare"
+)
+
+const (
+	runtimeVersion    = "0.1.0"
+	runtimeAPIVersion = "v1alpha2"
+)
+
+func init() {
+	runtime.Register(Name, runtime.Backend{
+		Dial: func() (runtime.Runtime, error) {
+			svc, err := NewService()
+			if err != nil {
+				return nil, err
+			}
+			return runtime.NewLocalClient(svc, svc, svc), nil
+		},
+		Handlers: []string{HandlerHost, HandlerUnshare},
+	})
+}
+
+// Service implements the CRI runtime and image services on top of host processes.
+// Images are ignored, the workload commands have to be available on the host.
+type Service struct {
+	dir        string
+	mu         sync.Mutex
+	sandboxes  map[string]*sandbox
+	containers map[string]*container
+}
+
+type sandbox struct {
+	id        string
+	config    *runtimeapi.PodSandboxConfig
+	handler   string
+	createdAt int64
+	state     runtimeapi.PodSandboxState
+}
+
+// NewService creates a service keeping its container directories in a fresh temporary directory.
+func NewService() (*Service, error) {
+	dir, err := ioutil.TempDir("", "touchstone-native")
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		dir:        dir,
+		sandboxes:  make(map[string]*sandbox),
+		containers: make(map[string]*container),
+	}, nil
+}
+
+// Close kills all remaining workloads and removes their directories.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	containers := make([]*container, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	s.containers = make(map[string]*container)
+	s.sandboxes = make(map[string]*sandbox)
+	s.mu.Unlock()
+	for _, c := range containers {
+		c.stop(0)
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func unimplemented(method string) error {
+	return status.Errorf(codes.Unimplemented, "native: %s is not supported", method)
+}
+
+func (s *Service) lookupSandbox(id string) (*sandbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sb, ok := s.sandboxes[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "native: sandbox %s not found", id)
+	}
+	return sb, nil
+}
+
+func (s *Service) lookupContainer(id string) (*container, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.containers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "native: container %s not found", id)
+	}
+	return c, nil
+}
+
+// sandboxContainers returns the containers belonging to the sandbox.
+func (s *Service) sandboxContainers(pod string) []*container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var containers []*container
+	for _, c := range s.containers {
+		if c.pod == pod {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+func (s *Service) Version(ctx context.Context, in *runtimeapi.VersionRequest, opts ...grpc.CallOption) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{
+		Version:           runtimeAPIVersion,
+		RuntimeName:       Name,
+		RuntimeVersion:    runtimeVersion,
+		RuntimeApiVersion: runtimeAPIVersion,
+	}, nil
+}
+
+func (s *Service) RunPodSandbox(ctx context.Context, in *runtimeapi.RunPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.RunPodSandboxResponse, error) {
+	handler := in.RuntimeHandler
+	if handler == "" {
+		handler = HandlerHost
+	}
+	if handler != HandlerHost && handler != HandlerUnshare {
+		return nil, status.Errorf(codes.InvalidArgument, "native: unknown runtime handler %q", handler)
+	}
+	sb := &sandbox{
+		id:        runtime.NewUUID(),
+		config:    in.Config,
+		handler:   handler,
+		createdAt: time.Now().UnixNano(),
+		state:     runtimeapi.PodSandboxState_SANDBOX_READY,
+	}
+	s.mu.Lock()
+	s.sandboxes[sb.id] = sb
+	s.mu.Unlock()
+	return &runtimeapi.RunPodSandboxResponse{PodSandboxId: sb.id}, nil
+}
+
+func (s *Service) StopPodSandbox(ctx context.Context, in *runtimeapi.StopPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.StopPodSandboxResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range s.sandboxContainers(sb.id) {
+		c.stop(0)
+	}
+	s.mu.Lock()
+	sb.state = runtimeapi.PodSandboxState_SANDBOX_NOTREADY
+	s.mu.Unlock()
+	return &runtimeapi.StopPodSandboxResponse{}, nil
+}
+
+func (s *Service) RemovePodSandbox(ctx context.Context, in *runtimeapi.RemovePodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.RemovePodSandboxResponse, error) {
+	for _, c := range s.sandboxContainers(in.PodSandboxId) {
+		if _, err := s.RemoveContainer(ctx, &runtimeapi.RemoveContainerRequest{ContainerId: c.id}); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	delete(s.sandboxes, in.PodSandboxId)
+	s.mu.Unlock()
+	return &runtimeapi.RemovePodSandboxResponse{}, nil
+}
+
+func (s *Service) PodSandboxStatus(ctx context.Context, in *runtimeapi.PodSandboxStatusRequest, opts ...grpc.CallOption) (*runtimeapi.PodSandboxStatusResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &runtimeapi.PodSandboxStatusResponse{
+		Status: sb.status(),
+	}, nil
+}
+
+func (sb *sandbox) status() *runtimeapi.PodSandboxStatus {
+	return &runtimeapi.PodSandboxStatus{
+		Id:             sb.id,
+		Metadata:       sb.config.GetMetadata(),
+		State:          sb.state,
+		CreatedAt:      sb.createdAt,
+		Labels:         sb.config.GetLabels(),
+		Annotations:    sb.config.GetAnnotations(),
+		RuntimeHandler: sb.handler,
+	}
+}
+
+func (s *Service) ListPodSandbox(ctx context.Context, in *runtimeapi.ListPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.ListPodSandboxResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]*runtimeapi.PodSandbox, 0, len(s.sandboxes))
+	for _, sb := range s.sandboxes {
+		items = append(items, &runtimeapi.PodSandbox{
+			Id:             sb.id,
+			Metadata:       sb.config.GetMetadata(),
+			State:          sb.state,
+			CreatedAt:      sb.createdAt,
+			Labels:         sb.config.GetLabels(),
+			Annotations:    sb.config.GetAnnotations(),
+			RuntimeHandler: sb.handler,
+		})
+	}
+	return &runtimeapi.ListPodSandboxResponse{Items: items}, nil
+}
+
+func (s *Service) CreateContainer(ctx context.Context, in *runtimeapi.CreateContainerRequest, opts ...grpc.CallOption) (*runtimeapi.CreateContainerResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	config := in.Config
+	if len(config.Command)+len(config.Args) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "native: container has no command")
+	}
+	if resources := config.GetLinux().GetResources(); resources != nil {
+		logrus.WithField("container", config.GetMetadata().GetName()).Warn("native runtime ignores container resource limits")
+	}
+	id := runtime.NewUUID()
+	dir := filepath.Join(s.dir, id)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	c := newContainer(id, sb.id, sb.handler, dir, config)
+	s.mu.Lock()
+	s.containers[id] = c
+	s.mu.Unlock()
+	return &runtimeapi.CreateContainerResponse{ContainerId: id}, nil
+}
+
+func (s *Service) StartContainer(ctx context.Context, in *runtimeapi.StartContainerRequest, opts ...grpc.CallOption) (*runtimeapi.StartContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.start(); err != nil {
+		return nil, status.Errorf(codes.Unknown, "native: failed to start container %s: %v", c.id, err)
+	}
+	return &runtimeapi.StartContainerResponse{}, nil
+}
+
+func (s *Service) StopContainer(ctx context.Context, in *runtimeapi.StopContainerRequest, opts ...grpc.CallOption) (*runtimeapi.StopContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	c.stop(time.Duration(in.Timeout) * time.Second)
+	return &runtimeapi.StopContainerResponse{}, nil
+}
+
+func (s *Service) RemoveContainer(ctx context.Context, in *runtimeapi.RemoveContainerRequest, opts ...grpc.CallOption) (*runtimeapi.RemoveContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	c.stop(0)
+	s.mu.Lock()
+	delete(s.containers, c.id)
+	s.mu.Unlock()
+	if err := os.RemoveAll(c.dir); err != nil {
+		return nil, err
+	}
+	return &runtimeapi.RemoveContainerResponse{}, nil
+}
+
+func (s *Service) ListContainers(ctx context.Context, in *runtimeapi.ListContainersRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error) {
+	s.mu.Lock()
+	containers := make([]*container, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	s.mu.Unlock()
+	items := make([]*runtimeapi.Container, 0, len(containers))
+	for _, c := range containers {
+		st := c.status()
+		items = append(items, &runtimeapi.Container{
+			Id:           st.Id,
+			PodSandboxId: c.pod,
+			Metadata:     st.Metadata,
+			Image:        st.Image,
+			ImageRef:     st.ImageRef,
+			State:        st.State,
+			CreatedAt:    st.CreatedAt,
+			Labels:       st.Labels,
+			Annotations:  st.Annotations,
+		})
+	}
+	return &runtimeapi.ListContainersResponse{Containers: items}, nil
+}
+
+func (s *Service) ContainerStatus(ctx context.Context, in *runtimeapi.ContainerStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	return &runtimeapi.ContainerStatusResponse{Status: c.status()}, nil
+}
+
+func (s *Service) UpdateContainerResources(ctx context.Context, in *runtimeapi.UpdateContainerResourcesRequest, opts ...grpc.CallOption) (*runtimeapi.UpdateContainerResourcesResponse, error) {
+	if _, err := s.lookupContainer(in.ContainerId); err != nil {
+		return nil, err
+	}
+	logrus.WithField("container", in.ContainerId).Warn("native runtime ignores container resource updates")
+	return &runtimeapi.UpdateContainerResourcesResponse{}, nil
+}
+
+func (s *Service) ReopenContainerLog(ctx context.Context, in *runtimeapi.ReopenContainerLogRequest, opts ...grpc.CallOption) (*runtimeapi.ReopenContainerLogResponse, error) {
+	return nil, unimplemented("ReopenContainerLog")
+}
+
+func (s *Service) ExecSync(ctx context.Context, in *runtimeapi.ExecSyncRequest, opts ...grpc.CallOption) (*runtimeapi.ExecSyncResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	return c.exec(ctx, in.Cmd, time.Duration(in.Timeout)*time.Second)
+}
+
+func (s *Service) Exec(ctx context.Context, in *runtimeapi.ExecRequest, opts ...grpc.CallOption) (*runtimeapi.ExecResponse, error) {
+	return nil, unimplemented("Exec")
+}
+
+func (s *Service) Attach(ctx context.Context, in *runtimeapi.AttachRequest, opts ...grpc.CallOption) (*runtimeapi.AttachResponse, error) {
+	return nil, unimplemented("Attach")
+}
+
+func (s *Service) PortForward(ctx context.Context, in *runtimeapi.PortForwardRequest, opts ...grpc.CallOption) (*runtimeapi.PortForwardResponse, error) {
+	return nil, unimplemented("PortForward")
+}
+
+func (s *Service) ContainerStats(ctx context.Context, in *runtimeapi.ContainerStatsRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatsResponse, error) {
+	return nil, unimplemented("ContainerStats")
+}
+
+func (s *Service) ListContainerStats(ctx context.Context, in *runtimeapi.ListContainerStatsRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainerStatsResponse, error) {
+	return nil, unimplemented("ListContainerStats")
+}
+
+func (s *Service) UpdateRuntimeConfig(ctx context.Context, in *runtimeapi.UpdateRuntimeConfigRequest, opts ...grpc.CallOption) (*runtimeapi.UpdateRuntimeConfigResponse, error) {
+	return &runtimeapi.UpdateRuntimeConfigResponse{}, nil
+}
+
+func (s *Service) Status(ctx context.Context, in *runtimeapi.StatusRequest, opts ...grpc.CallOption) (*runtimeapi.StatusResponse, error) {
+	resp := &runtimeapi.StatusResponse{
+		Status: &runtimeapi.RuntimeStatus{
+			Conditions: []*runtimeapi.RuntimeCondition{
+				{Type: runtimeapi.RuntimeReady, Status: true},
+				{Type: runtimeapi.NetworkReady, Status: true},
+			},
+		},
+	}
+	if in.Verbose {
+		resp.Info = map[string]string{
+			"handlers": strings.Join([]string{HandlerHost, HandlerUnshare}, ","),
+			"dir":      s.dir,
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) ListImages(ctx context.Context, in *runtimeapi.ListImagesRequest, opts ...grpc.CallOption) (*runtimeapi.ListImagesResponse, error) {
+	return &runtimeapi.ListImagesResponse{}, nil
+}
+
+func (s *Service) ImageStatus(ctx context.Context, in *runtimeapi.ImageStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ImageStatusResponse, error) {
+	return &runtimeapi.ImageStatusResponse{
+		Image: &runtimeapi.Image{
+			Id:       in.GetImage().GetImage(),
+			RepoTags: []string{in.GetImage().GetImage()},
+		},
+	}, nil
+}
+
+// PullImage does nothing, workloads run with the binaries installed on the host.
+func (s *Service) PullImage(ctx context.Context, in *runtimeapi.PullImageRequest, opts ...grpc.CallOption) (*runtimeapi.PullImageResponse, error) {
+	return &runtimeapi.PullImageResponse{ImageRef: in.GetImage().GetImage()}, nil
+}
+
+func (s *Service) RemoveImage(ctx context.Context, in *runtimeapi.RemoveImageRequest, opts ...grpc.CallOption) (*runtimeapi.RemoveImageResponse, error) {
+	return &runtimeapi.RemoveImageResponse{}, nil
+}
+
+func (s *Service) ImageFsInfo(ctx context.Context, in *runtimeapi.ImageFsInfoRequest, opts ...grpc.CallOption) (*runtimeapi.ImageFsInfoResponse, error) {
+	return nil, unimplemented("ImageFsInfo")
+}
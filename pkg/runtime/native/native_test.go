@@ -0,0 +1,94 @@
+package native
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestService(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Command  []string
+		Stdout   string
+		Stderr   string
+		ExitCode int32
+	}{
+		{
+			Name:    "success",
+			Command: []string{"sh", "-c", "echo hello && echo world"},
+			Stdout:  "hello\nworld\n",
+		},
+		{
+			Name:     "failure",
+			Command:  []string{"sh", "-c", "echo oops >&2; exit 3"},
+			Stderr:   "oops\n",
+			ExitCode: 3,
+		},
+		{
+			Name:    "partial",
+			Command: []string{"printf", "no newline"},
+			Stdout:  "no newline\n",
+		},
+	}
+	logDir, err := ioutil.TempDir("", "native_test")
+	if err != nil {
+		t.Fatalf("could not create log dir: %v", err)
+	}
+	defer os.RemoveAll(logDir)
+	svc, err := NewService()
+	if err != nil {
+		t.Fatalf("could not create service: %v", err)
+	}
+	client := runtime.NewLocalClient(svc, svc, svc)
+	defer client.Close()
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			sandbox, err := runtime.RunSandbox(client, tc.Name, HandlerHost)
+			if err != nil {
+				t.Fatalf("could not run sandbox: %v", err)
+			}
+			defer runtime.StopAndRemoveSandbox(client, sandbox.ID)
+			resp, err := svc.CreateContainer(context.Background(), &runtimeapi.CreateContainerRequest{
+				PodSandboxId: sandbox.ID,
+				Config: &runtimeapi.ContainerConfig{
+					Metadata: &runtimeapi.ContainerMetadata{Name: tc.Name},
+					Command:  tc.Command,
+					LogPath:  filepath.Join(logDir, tc.Name+".log"),
+				},
+			})
+			if err != nil {
+				t.Fatalf("could not create container: %v", err)
+			}
+			if err := client.StartContainer(resp.ContainerId); err != nil {
+				t.Fatalf("could not start container: %v", err)
+			}
+			status, err := runtime.WaitForExit(client, resp.ContainerId)
+			if err != nil {
+				t.Fatalf("could not wait for container: %v", err)
+			}
+			if status.ExitCode != tc.ExitCode {
+				t.Errorf("expected exit code %d, got %d", tc.ExitCode, status.ExitCode)
+			}
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			if err := client.LogStreams(resp.ContainerId, stdout, stderr); err != nil {
+				t.Fatalf("could not read logs: %v", err)
+			}
+			if stdout.String() != tc.Stdout {
+				t.Errorf("expected stdout %q, got %q", tc.Stdout, stdout.String())
+			}
+			if stderr.String() != tc.Stderr {
+				t.Errorf("expected stderr %q, got %q", tc.Stderr, stderr.String())
+			}
+			if err := runtime.StopAndRemoveContainer(client, resp.ContainerId); err != nil {
+				t.Fatalf("could not remove container: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package ocidirect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime/crilog"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const (
+	// logDrainTimeout limits the time waiting for the output pipes after the container exited.
+	logDrainTimeout   = time.Second
+	statePollInterval = 10 * time.Millisecond
+)
+
+// waitingRuntimes lists the runtime binaries with a wait command reporting the exit status of a container.
+// Their pid file names the sandbox process instead of the container process, so it can not be waited for.
+var waitingRuntimes = map[string]bool{
+	"runsc": true,
+}
+
+// container is an OCI container managed through the runtime binary.
+type container struct {
+	id       string
+	pod      string
+	binary   string
+	stateDir string
+	bundle   string
+	config   *runtimeapi.ContainerConfig
+
+	// mu guards the fields below, which change while the container runs
+	mu         sync.Mutex
+	pid        int
+	state      runtimeapi.ContainerState
+	createdAt  int64
+	startedAt  int64
+	finishedAt int64
+	exitCode   int32
+	reason     string
+	message    string
+	done       chan struct{}
+}
+
+// command builds an invocation of the runtime binary using the state directory of the service.
+func (c *container) command(args ...string) *exec.Cmd {
+	return exec.Command(c.binary, append([]string{
+		"--root", c.stateDir,
+		"--log", filepath.Join(c.bundle, "runtime.log"),
+	}, args...)...)
+}
+
+// run invokes the runtime binary and includes its output in errors.
+func (c *container) run(args ...string) error {
+	out, err := c.command(args...).CombinedOutput()
+	if err != nil {
+		return errors.Errorf("%s %s: %v: %s", filepath.Base(c.binary), args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// create generates the bundle on top of the image and creates the container.
+// The output of the container is passed through pipes into the CRI log file.
+func (c *container) create(rootfs string) (err error) {
+	for _, dir := range []string{"rootfs", "upper", "work"} {
+		if err := os.MkdirAll(filepath.Join(c.bundle, dir), 0755); err != nil {
+			return err
+		}
+	}
+	if err := mountOverlay(rootfs, c.bundle); err != nil {
+		os.RemoveAll(c.bundle)
+		return err
+	}
+	defer func() {
+		if err != nil {
+			unmountOverlay(c.bundle)
+			os.RemoveAll(c.bundle)
+		}
+	}()
+	spec, err := json.Marshal(NewSpec(c.config))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(c.bundle, "config.json"), spec, 0644); err != nil {
+		return err
+	}
+	log, err := crilog.Open(c.config.LogPath)
+	if err != nil {
+		return err
+	}
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		log.Close()
+		return err
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		log.Close()
+		stdoutR.Close()
+		stdoutW.Close()
+		return err
+	}
+	pidFile := filepath.Join(c.bundle, "pid")
+	cmd := c.command("create", "--bundle", c.bundle, "--pid-file", pidFile, c.id)
+	cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+	c.mu.Lock()
+	c.createdAt = time.Now().UnixNano()
+	c.mu.Unlock()
+	err = cmd.Run()
+	// The container holds the write ends now
+	stdoutW.Close()
+	stderrW.Close()
+	if err != nil {
+		stdoutR.Close()
+		stderrR.Close()
+		log.Close()
+		runtimeLog, _ := ioutil.ReadFile(filepath.Join(c.bundle, "runtime.log"))
+		return errors.Errorf("%s create: %v: %s", filepath.Base(c.binary), err, strings.TrimSpace(string(runtimeLog)))
+	}
+	var pid int
+	data, err := ioutil.ReadFile(pidFile)
+	if err == nil {
+		pid, err = strconv.Atoi(strings.TrimSpace(string(data)))
+	}
+	if err != nil {
+		stdoutR.Close()
+		stderrR.Close()
+		log.Close()
+		return errors.Wrap(err, "failed to read pid file")
+	}
+	c.mu.Lock()
+	c.pid = pid
+	c.state = runtimeapi.ContainerState_CONTAINER_CREATED
+	c.mu.Unlock()
+	var drained sync.WaitGroup
+	drained.Add(2)
+	go drain(&drained, log.Stream("stdout"), stdoutR)
+	go drain(&drained, log.Stream("stderr"), stderrR)
+	go c.wait(log, &drained)
+	return nil
+}
+
+func drain(wg *sync.WaitGroup, w io.Writer, r *os.File) {
+	defer wg.Done()
+	defer r.Close()
+	io.Copy(w, r)
+}
+
+// wait reaps the container process and records its exit once the logs are drained.
+func (c *container) wait(log *crilog.File, drained *sync.WaitGroup) {
+	exitCode, reason, message := c.reap()
+	drainedCh := make(chan struct{})
+	go func() {
+		drained.Wait()
+		close(drainedCh)
+	}()
+	select {
+	case <-drainedCh:
+	case <-time.After(logDrainTimeout):
+		logrus.WithField("container", c.id).Warn("container output still open after exit")
+	}
+	log.Close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finishedAt = time.Now().UnixNano()
+	c.state = runtimeapi.ContainerState_CONTAINER_EXITED
+	c.exitCode, c.reason, c.message = exitCode, reason, message
+	close(c.done)
+}
+
+// reap waits for the container to exit and returns its exit code, reason and message.
+// Runtimes with a wait command report the exit status themselves. For the others the process of the
+// pid file is the container process, which has been reparented to touchstone as child subreaper.
+// If neither works it polls the runtime state, losing the exit code.
+func (c *container) reap() (int32, string, string) {
+	c.mu.Lock()
+	pid := c.pid
+	c.mu.Unlock()
+	if waitingRuntimes[filepath.Base(c.binary)] {
+		// collect the sandbox process once it exits to not leave a zombie behind
+		go waitPid(pid)
+		code, err := c.runtimeWait()
+		if err == nil {
+			return exitResult(code)
+		}
+		logrus.WithError(err).WithField("container", c.id).Warn("failed to wait for container")
+	} else if ws, err := waitPid(pid); err == nil {
+		if ws.Signaled() {
+			return exitResult(128 + int32(ws.Signal()))
+		}
+		return exitResult(int32(ws.ExitStatus()))
+	}
+	for {
+		state, err := c.runtimeState()
+		if err != nil || state == "stopped" {
+			return -1, "Unknown", "exit code unavailable"
+		}
+		time.Sleep(statePollInterval)
+	}
+}
+
+// waitPid waits for the child process to exit.
+func waitPid(pid int) (syscall.WaitStatus, error) {
+	var ws syscall.WaitStatus
+	for {
+		_, err := syscall.Wait4(pid, &ws, 0, nil)
+		if err != syscall.EINTR {
+			return ws, err
+		}
+	}
+}
+
+// exitResult converts an exit code into the CRI exit code, reason and message.
+func exitResult(code int32) (int32, string, string) {
+	if code != 0 {
+		return code, "Error", ""
+	}
+	return 0, "Completed", ""
+}
+
+// runtimeWait blocks in the wait command of the runtime binary until the container exits.
+// The reported exit status is 128 plus the signal number for killed containers.
+func (c *container) runtimeWait() (int32, error) {
+	out, err := c.command("wait", c.id).Output()
+	if err != nil {
+		return 0, err
+	}
+	var result struct {
+		ExitStatus int32 `json:"exitStatus"`
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return 0, err
+	}
+	return result.ExitStatus, nil
+}
+
+// runtimeState queries the container status reported by the runtime binary.
+func (c *container) runtimeState() (string, error) {
+	out, err := c.command("state", c.id).Output()
+	if err != nil {
+		return "", err
+	}
+	var state struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal(out, &state); err != nil {
+		return "", err
+	}
+	return state.Status, nil
+}
+
+func (c *container) start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state != runtimeapi.ContainerState_CONTAINER_CREATED {
+		return errors.New("container already started")
+	}
+	if err := c.run("start", c.id); err != nil {
+		return err
+	}
+	c.state = runtimeapi.ContainerState_CONTAINER_RUNNING
+	c.startedAt = time.Now().UnixNano()
+	return nil
+}
+
+// stop sends SIGTERM and kills the container after the timeout.
+func (c *container) stop(timeout time.Duration) error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	if timeout > 0 {
+		if err := c.run("kill", c.id, "TERM"); err == nil {
+			select {
+			case <-c.done:
+				return nil
+			case <-time.After(timeout):
+			}
+		}
+	}
+	if err := c.run("kill", c.id, "KILL"); err != nil {
+		// The container may have exited in the meantime
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(logDrainTimeout):
+			return err
+		}
+	}
+	<-c.done
+	return nil
+}
+
+// remove deletes the container and its bundle.
+func (c *container) remove() error {
+	if err := c.run("delete", "--force", c.id); err != nil {
+		return err
+	}
+	<-c.done
+	if err := unmountOverlay(c.bundle); err != nil {
+		return err
+	}
+	return os.RemoveAll(c.bundle)
+}
+
+func (c *container) update(resources *Resources) error {
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return err
+	}
+	cmd := c.command("update", "--resources", "-", c.id)
+	cmd.Stdin = bytes.NewReader(data)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Errorf("%s update: %v: %s", filepath.Base(c.binary), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// exec runs a command in the container and captures its output.
+func (c *container) exec(ctx context.Context, argv []string, timeout time.Duration) (*runtimeapi.ExecSyncResponse, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("empty command")
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd := c.command(append([]string{"exec", c.id}, argv...)...)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+	err := cmd.Wait()
+	close(done)
+	resp := &runtimeapi.ExecSyncResponse{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		resp.ExitCode = int32(exitErr.ExitCode())
+	} else if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *container) status() *runtimeapi.ContainerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &runtimeapi.ContainerStatus{
+		Id:          c.id,
+		Metadata:    c.config.Metadata,
+		State:       c.state,
+		CreatedAt:   c.createdAt,
+		StartedAt:   c.startedAt,
+		FinishedAt:  c.finishedAt,
+		ExitCode:    c.exitCode,
+		Image:       c.config.Image,
+		ImageRef:    imageRootfs(c.config.GetImage().GetImage()),
+		Reason:      c.reason,
+		Message:     c.message,
+		Labels:      c.config.Labels,
+		Annotations: c.config.Annotations,
+		Mounts:      c.config.Mounts,
+		LogPath:     c.config.LogPath,
+	}
+}
+
+// mountOverlay mounts a writable overlay of the image into the rootfs directory of the bundle.
+func mountOverlay(image, bundle string) error {
+	options := "lowerdir=" + image +
+		",upperdir=" + filepath.Join(bundle, "upper") +
+		",workdir=" + filepath.Join(bundle, "work")
+	if err := syscall.Mount("overlay", filepath.Join(bundle, "rootfs"), "overlay", 0, options); err != nil {
+		return errors.Wrap(err, "failed to mount rootfs")
+	}
+	return nil
+}
+
+func unmountOverlay(bundle string) error {
+	if err := syscall.Unmount(filepath.Join(bundle, "rootfs"), 0); err != nil && err != syscall.EINVAL {
+		return errors.Wrap(err, "failed to unmount rootfs")
+	}
+	return nil
+}
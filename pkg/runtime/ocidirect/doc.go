@@ -0,0 +1,9 @@
+// Package ocidirect drives OCI runtime binaries like runc and runsc directly, bypassing the CRI.
+// It isolates the time spent in the runtime binary from the overhead of containerd and CRI-O.
+//
+// Images are not pulled. They have to be unpacked into the image directory beforehand,
+// see ImageDir.
+package ocidirect
+
+// Name is the CRI name of the direct OCI backend.
+const Name = "oci-direct"
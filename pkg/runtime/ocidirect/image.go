@@ -0,0 +1,37 @@
+package ocidirect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ImageDirEnv overrides the default image directory.
+const ImageDirEnv = "TOUCHSTONE_IMAGE_DIR"
+
+const defaultImageDir = "/var/lib/touchstone/images"
+
+// ImageDir returns the directory holding the unpacked root filesystems.
+// Each image is stored in a subdirectory named after its reference with slashes and colons
+// replaced by underscores, e.g. lnsp_sysbench_latest for lnsp/sysbench:latest.
+func ImageDir() string {
+	if dir := os.Getenv(ImageDirEnv); dir != "" {
+		return dir
+	}
+	return defaultImageDir
+}
+
+// imageRootfs returns the root filesystem directory of the image.
+func imageRootfs(image string) string {
+	if !strings.Contains(image, ":") {
+		image += ":latest"
+	}
+	name := strings.NewReplacer("/", "_", ":", "_").Replace(image)
+	return filepath.Join(ImageDir(), name)
+}
+
+// imageExists checks that the image has been unpacked.
+func imageExists(image string) bool {
+	info, err := os.Stat(imageRootfs(image))
+	return err == nil && info.IsDir()
+}
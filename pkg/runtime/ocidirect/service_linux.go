@@ -0,0 +1,423 @@
+package ocidirect
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/runtime"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const (
+	runtimeVersion    = "0.1.0"
+	runtimeAPIVersion = "v1alpha2"
+	// prSetChildSubreaper makes orphaned container processes children of touchstone, see prctl(2).
+	prSetChildSubreaper = 36
+)
+
+func init() {
+	runtime.Register(Name, runtime.Backend{
+		Dial: func() (runtime.Runtime, error) {
+			svc, err := NewService()
+			if err != nil {
+				return nil, err
+			}
+			return runtime.NewLocalClient(svc, svc, svc), nil
+		},
+	})
+}
+
+// Service implements the CRI runtime and image services by invoking an OCI runtime binary.
+// The runtime handler of a sandbox names the binary used for its containers.
+type Service struct {
+	dir        string
+	mu         sync.Mutex
+	sandboxes  map[string]*sandbox
+	containers map[string]*container
+}
+
+type sandbox struct {
+	id        string
+	config    *runtimeapi.PodSandboxConfig
+	handler   string
+	binary    string
+	createdAt int64
+	state     runtimeapi.PodSandboxState
+}
+
+// NewService creates a service keeping bundles and runtime state in a fresh temporary directory.
+// It registers touchstone as child subreaper to collect the exit codes of detached containers.
+func NewService() (*Service, error) {
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, 1, 0); errno != 0 {
+		return nil, errno
+	}
+	dir, err := ioutil.TempDir("", "touchstone-oci")
+	if err != nil {
+		return nil, err
+	}
+	return &Service{
+		dir:        dir,
+		sandboxes:  make(map[string]*sandbox),
+		containers: make(map[string]*container),
+	}, nil
+}
+
+// Close removes all remaining containers and their bundles.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	containers := make([]*container, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	s.containers = make(map[string]*container)
+	s.sandboxes = make(map[string]*sandbox)
+	s.mu.Unlock()
+	for _, c := range containers {
+		if err := c.remove(); err != nil {
+			logrus.WithError(err).WithField("container", c.id).Warn("failed to remove container")
+		}
+	}
+	return os.RemoveAll(s.dir)
+}
+
+func unimplemented(method string) error {
+	return status.Errorf(codes.Unimplemented, "oci-direct: %s is not supported", method)
+}
+
+func (s *Service) lookupSandbox(id string) (*sandbox, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sb, ok := s.sandboxes[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "oci-direct: sandbox %s not found", id)
+	}
+	return sb, nil
+}
+
+func (s *Service) lookupContainer(id string) (*container, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.containers[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "oci-direct: container %s not found", id)
+	}
+	return c, nil
+}
+
+// sandboxContainers returns the containers belonging to the sandbox.
+func (s *Service) sandboxContainers(pod string) []*container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var containers []*container
+	for _, c := range s.containers {
+		if c.pod == pod {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+func (s *Service) Version(ctx context.Context, in *runtimeapi.VersionRequest, opts ...grpc.CallOption) (*runtimeapi.VersionResponse, error) {
+	return &runtimeapi.VersionResponse{
+		Version:           runtimeAPIVersion,
+		RuntimeName:       Name,
+		RuntimeVersion:    runtimeVersion,
+		RuntimeApiVersion: runtimeAPIVersion,
+	}, nil
+}
+
+// RunPodSandbox only records the sandbox, the OCI runtime has no notion of pods.
+func (s *Service) RunPodSandbox(ctx context.Context, in *runtimeapi.RunPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.RunPodSandboxResponse, error) {
+	binary, err := exec.LookPath(in.RuntimeHandler)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "oci-direct: runtime handler %q: %v", in.RuntimeHandler, err)
+	}
+	sb := &sandbox{
+		id:        runtime.NewUUID(),
+		config:    in.Config,
+		handler:   in.RuntimeHandler,
+		binary:    binary,
+		createdAt: time.Now().UnixNano(),
+		state:     runtimeapi.PodSandboxState_SANDBOX_READY,
+	}
+	s.mu.Lock()
+	s.sandboxes[sb.id] = sb
+	s.mu.Unlock()
+	return &runtimeapi.RunPodSandboxResponse{PodSandboxId: sb.id}, nil
+}
+
+func (s *Service) StopPodSandbox(ctx context.Context, in *runtimeapi.StopPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.StopPodSandboxResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range s.sandboxContainers(sb.id) {
+		if err := c.stop(0); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	sb.state = runtimeapi.PodSandboxState_SANDBOX_NOTREADY
+	s.mu.Unlock()
+	return &runtimeapi.StopPodSandboxResponse{}, nil
+}
+
+func (s *Service) RemovePodSandbox(ctx context.Context, in *runtimeapi.RemovePodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.RemovePodSandboxResponse, error) {
+	for _, c := range s.sandboxContainers(in.PodSandboxId) {
+		if _, err := s.RemoveContainer(ctx, &runtimeapi.RemoveContainerRequest{ContainerId: c.id}); err != nil {
+			return nil, err
+		}
+	}
+	s.mu.Lock()
+	delete(s.sandboxes, in.PodSandboxId)
+	s.mu.Unlock()
+	return &runtimeapi.RemovePodSandboxResponse{}, nil
+}
+
+func (s *Service) PodSandboxStatus(ctx context.Context, in *runtimeapi.PodSandboxStatusRequest, opts ...grpc.CallOption) (*runtimeapi.PodSandboxStatusResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &runtimeapi.PodSandboxStatusResponse{
+		Status: &runtimeapi.PodSandboxStatus{
+			Id:             sb.id,
+			Metadata:       sb.config.GetMetadata(),
+			State:          sb.state,
+			CreatedAt:      sb.createdAt,
+			Labels:         sb.config.GetLabels(),
+			Annotations:    sb.config.GetAnnotations(),
+			RuntimeHandler: sb.handler,
+		},
+	}, nil
+}
+
+func (s *Service) ListPodSandbox(ctx context.Context, in *runtimeapi.ListPodSandboxRequest, opts ...grpc.CallOption) (*runtimeapi.ListPodSandboxResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]*runtimeapi.PodSandbox, 0, len(s.sandboxes))
+	for _, sb := range s.sandboxes {
+		items = append(items, &runtimeapi.PodSandbox{
+			Id:             sb.id,
+			Metadata:       sb.config.GetMetadata(),
+			State:          sb.state,
+			CreatedAt:      sb.createdAt,
+			Labels:         sb.config.GetLabels(),
+			Annotations:    sb.config.GetAnnotations(),
+			RuntimeHandler: sb.handler,
+		})
+	}
+	return &runtimeapi.ListPodSandboxResponse{Items: items}, nil
+}
+
+// CreateContainer generates the bundle and calls the create command of the runtime.
+func (s *Service) CreateContainer(ctx context.Context, in *runtimeapi.CreateContainerRequest, opts ...grpc.CallOption) (*runtimeapi.CreateContainerResponse, error) {
+	sb, err := s.lookupSandbox(in.PodSandboxId)
+	if err != nil {
+		return nil, err
+	}
+	config := in.Config
+	if len(config.Command)+len(config.Args) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "oci-direct: container has no command")
+	}
+	image := config.GetImage().GetImage()
+	if !imageExists(image) {
+		return nil, status.Errorf(codes.NotFound, "oci-direct: image %s is not unpacked in %s", image, imageRootfs(image))
+	}
+	c := &container{
+		id:       runtime.NewUUID(),
+		pod:      sb.id,
+		binary:   sb.binary,
+		stateDir: filepath.Join(s.dir, "state"),
+		config:   config,
+		done:     make(chan struct{}),
+	}
+	c.bundle = filepath.Join(s.dir, "bundles", c.id)
+	if err := c.create(imageRootfs(image)); err != nil {
+		return nil, status.Errorf(codes.Unknown, "oci-direct: failed to create container: %v", err)
+	}
+	s.mu.Lock()
+	s.containers[c.id] = c
+	s.mu.Unlock()
+	return &runtimeapi.CreateContainerResponse{ContainerId: c.id}, nil
+}
+
+func (s *Service) StartContainer(ctx context.Context, in *runtimeapi.StartContainerRequest, opts ...grpc.CallOption) (*runtimeapi.StartContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.start(); err != nil {
+		return nil, status.Errorf(codes.Unknown, "oci-direct: failed to start container %s: %v", c.id, err)
+	}
+	return &runtimeapi.StartContainerResponse{}, nil
+}
+
+func (s *Service) StopContainer(ctx context.Context, in *runtimeapi.StopContainerRequest, opts ...grpc.CallOption) (*runtimeapi.StopContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.stop(time.Duration(in.Timeout) * time.Second); err != nil {
+		return nil, status.Errorf(codes.Unknown, "oci-direct: failed to stop container %s: %v", c.id, err)
+	}
+	return &runtimeapi.StopContainerResponse{}, nil
+}
+
+func (s *Service) RemoveContainer(ctx context.Context, in *runtimeapi.RemoveContainerRequest, opts ...grpc.CallOption) (*runtimeapi.RemoveContainerResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.remove(); err != nil {
+		return nil, status.Errorf(codes.Unknown, "oci-direct: failed to remove container %s: %v", c.id, err)
+	}
+	s.mu.Lock()
+	delete(s.containers, c.id)
+	s.mu.Unlock()
+	return &runtimeapi.RemoveContainerResponse{}, nil
+}
+
+func (s *Service) ListContainers(ctx context.Context, in *runtimeapi.ListContainersRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainersResponse, error) {
+	s.mu.Lock()
+	containers := make([]*container, 0, len(s.containers))
+	for _, c := range s.containers {
+		containers = append(containers, c)
+	}
+	s.mu.Unlock()
+	items := make([]*runtimeapi.Container, 0, len(containers))
+	for _, c := range containers {
+		st := c.status()
+		items = append(items, &runtimeapi.Container{
+			Id:           st.Id,
+			PodSandboxId: c.pod,
+			Metadata:     st.Metadata,
+			Image:        st.Image,
+			ImageRef:     st.ImageRef,
+			State:        st.State,
+			CreatedAt:    st.CreatedAt,
+			Labels:       st.Labels,
+			Annotations:  st.Annotations,
+		})
+	}
+	return &runtimeapi.ListContainersResponse{Containers: items}, nil
+}
+
+func (s *Service) ContainerStatus(ctx context.Context, in *runtimeapi.ContainerStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatusResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	return &runtimeapi.ContainerStatusResponse{Status: c.status()}, nil
+}
+
+func (s *Service) UpdateContainerResources(ctx context.Context, in *runtimeapi.UpdateContainerResourcesRequest, opts ...grpc.CallOption) (*runtimeapi.UpdateContainerResourcesResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.update(NewResources(in.Linux)); err != nil {
+		return nil, status.Errorf(codes.Unknown, "oci-direct: failed to update container %s: %v", c.id, err)
+	}
+	return &runtimeapi.UpdateContainerResourcesResponse{}, nil
+}
+
+func (s *Service) ReopenContainerLog(ctx context.Context, in *runtimeapi.ReopenContainerLogRequest, opts ...grpc.CallOption) (*runtimeapi.ReopenContainerLogResponse, error) {
+	return nil, unimplemented("ReopenContainerLog")
+}
+
+func (s *Service) ExecSync(ctx context.Context, in *runtimeapi.ExecSyncRequest, opts ...grpc.CallOption) (*runtimeapi.ExecSyncResponse, error) {
+	c, err := s.lookupContainer(in.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+	return c.exec(ctx, in.Cmd, time.Duration(in.Timeout)*time.Second)
+}
+
+func (s *Service) Exec(ctx context.Context, in *runtimeapi.ExecRequest, opts ...grpc.CallOption) (*runtimeapi.ExecResponse, error) {
+	return nil, unimplemented("Exec")
+}
+
+func (s *Service) Attach(ctx context.Context, in *runtimeapi.AttachRequest, opts ...grpc.CallOption) (*runtimeapi.AttachResponse, error) {
+	return nil, unimplemented("Attach")
+}
+
+func (s *Service) PortForward(ctx context.Context, in *runtimeapi.PortForwardRequest, opts ...grpc.CallOption) (*runtimeapi.PortForwardResponse, error) {
+	return nil, unimplemented("PortForward")
+}
+
+func (s *Service) ContainerStats(ctx context.Context, in *runtimeapi.ContainerStatsRequest, opts ...grpc.CallOption) (*runtimeapi.ContainerStatsResponse, error) {
+	return nil, unimplemented("ContainerStats")
+}
+
+func (s *Service) ListContainerStats(ctx context.Context, in *runtimeapi.ListContainerStatsRequest, opts ...grpc.CallOption) (*runtimeapi.ListContainerStatsResponse, error) {
+	return nil, unimplemented("ListContainerStats")
+}
+
+func (s *Service) UpdateRuntimeConfig(ctx context.Context, in *runtimeapi.UpdateRuntimeConfigRequest, opts ...grpc.CallOption) (*runtimeapi.UpdateRuntimeConfigResponse, error) {
+	return &runtimeapi.UpdateRuntimeConfigResponse{}, nil
+}
+
+func (s *Service) Status(ctx context.Context, in *runtimeapi.StatusRequest, opts ...grpc.CallOption) (*runtimeapi.StatusResponse, error) {
+	resp := &runtimeapi.StatusResponse{
+		Status: &runtimeapi.RuntimeStatus{
+			Conditions: []*runtimeapi.RuntimeCondition{
+				{Type: runtimeapi.RuntimeReady, Status: true},
+				{Type: runtimeapi.NetworkReady, Status: true},
+			},
+		},
+	}
+	if in.Verbose {
+		resp.Info = map[string]string{
+			"imageDir": ImageDir(),
+			"dir":      s.dir,
+		}
+	}
+	return resp, nil
+}
+
+func (s *Service) ListImages(ctx context.Context, in *runtimeapi.ListImagesRequest, opts ...grpc.CallOption) (*runtimeapi.ListImagesResponse, error) {
+	return &runtimeapi.ListImagesResponse{}, nil
+}
+
+func (s *Service) ImageStatus(ctx context.Context, in *runtimeapi.ImageStatusRequest, opts ...grpc.CallOption) (*runtimeapi.ImageStatusResponse, error) {
+	image := in.GetImage().GetImage()
+	if !imageExists(image) {
+		return &runtimeapi.ImageStatusResponse{}, nil
+	}
+	return &runtimeapi.ImageStatusResponse{
+		Image: &runtimeapi.Image{
+			Id:       imageRootfs(image),
+			RepoTags: []string{image},
+		},
+	}, nil
+}
+
+// PullImage verifies that the image has been unpacked into the image directory.
+func (s *Service) PullImage(ctx context.Context, in *runtimeapi.PullImageRequest, opts ...grpc.CallOption) (*runtimeapi.PullImageResponse, error) {
+	image := in.GetImage().GetImage()
+	if !imageExists(image) {
+		return nil, status.Errorf(codes.NotFound, "oci-direct: image %s is not unpacked in %s", image, imageRootfs(image))
+	}
+	return &runtimeapi.PullImageResponse{ImageRef: imageRootfs(image)}, nil
+}
+
+func (s *Service) RemoveImage(ctx context.Context, in *runtimeapi.RemoveImageRequest, opts ...grpc.CallOption) (*runtimeapi.RemoveImageResponse, error) {
+	return &runtimeapi.RemoveImageResponse{}, nil
+}
+
+func (s *Service) ImageFsInfo(ctx context.Context, in *runtimeapi.ImageFsInfoRequest, opts ...grpc.CallOption) (*runtimeapi.ImageFsInfoResponse, error) {
+	return nil, unimplemented("ImageFsInfo")
+}
@@ -0,0 +1,189 @@
+package ocidirect
+
+import (
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+const ociVersion = "1.0.1"
+
+// defaultPath is used since unpacked root filesystems carry no image configuration.
+const defaultPath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+var defaultCapabilities = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_FSETID", "CAP_FOWNER", "CAP_MKNOD",
+	"CAP_NET_RAW", "CAP_SETGID", "CAP_SETUID", "CAP_SETFCAP", "CAP_SETPCAP",
+	"CAP_NET_BIND_SERVICE", "CAP_SYS_CHROOT", "CAP_KILL", "CAP_AUDIT_WRITE",
+}
+
+// Spec is the subset of the OCI runtime specification used for bundles.
+type Spec struct {
+	Version  string  `json:"ociVersion"`
+	Process  Process `json:"process"`
+	Root     Root    `json:"root"`
+	Hostname string  `json:"hostname,omitempty"`
+	Mounts   []Mount `json:"mounts"`
+	Linux    Linux   `json:"linux"`
+}
+
+type Process struct {
+	Terminal        bool          `json:"terminal"`
+	User            User          `json:"user"`
+	Args            []string      `json:"args"`
+	Env             []string      `json:"env,omitempty"`
+	Cwd             string        `json:"cwd"`
+	Capabilities    *Capabilities `json:"capabilities,omitempty"`
+	Rlimits         []Rlimit      `json:"rlimits,omitempty"`
+	NoNewPrivileges bool          `json:"noNewPrivileges"`
+}
+
+type User struct {
+	UID uint32 `json:"uid"`
+	GID uint32 `json:"gid"`
+}
+
+type Capabilities struct {
+	Bounding    []string `json:"bounding"`
+	Effective   []string `json:"effective"`
+	Inheritable []string `json:"inheritable"`
+	Permitted   []string `json:"permitted"`
+}
+
+type Rlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+type Root struct {
+	Path     string `json:"path"`
+	Readonly bool   `json:"readonly"`
+}
+
+type Mount struct {
+	Destination string   `json:"destination"`
+	Type        string   `json:"type,omitempty"`
+	Source      string   `json:"source,omitempty"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type Linux struct {
+	Namespaces    []Namespace `json:"namespaces"`
+	Resources     *Resources  `json:"resources,omitempty"`
+	MaskedPaths   []string    `json:"maskedPaths,omitempty"`
+	ReadonlyPaths []string    `json:"readonlyPaths,omitempty"`
+}
+
+type Namespace struct {
+	Type string `json:"type"`
+}
+
+type Resources struct {
+	CPU    *CPU    `json:"cpu,omitempty"`
+	Memory *Memory `json:"memory,omitempty"`
+}
+
+type CPU struct {
+	Shares *uint64 `json:"shares,omitempty"`
+	Quota  *int64  `json:"quota,omitempty"`
+	Period *uint64 `json:"period,omitempty"`
+	Cpus   string  `json:"cpus,omitempty"`
+	Mems   string  `json:"mems,omitempty"`
+}
+
+type Memory struct {
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// NewSpec generates the bundle configuration of a CRI container. The root filesystem is expected
+// in the rootfs directory of the bundle.
+func NewSpec(config *runtimeapi.ContainerConfig) *Spec {
+	args := append(append([]string{}, config.Command...), config.Args...)
+	env := []string{defaultPath}
+	for _, kv := range config.Envs {
+		env = append(env, kv.Key+"="+kv.Value)
+	}
+	cwd := config.WorkingDir
+	if cwd == "" {
+		cwd = "/"
+	}
+	spec := &Spec{
+		Version: ociVersion,
+		Process: Process{
+			Args: args,
+			Env:  env,
+			Cwd:  cwd,
+			Capabilities: &Capabilities{
+				Bounding:    defaultCapabilities,
+				Effective:   defaultCapabilities,
+				Inheritable: defaultCapabilities,
+				Permitted:   defaultCapabilities,
+			},
+			Rlimits: []Rlimit{
+				{Type: "RLIMIT_NOFILE", Hard: 1024, Soft: 1024},
+			},
+			NoNewPrivileges: true,
+		},
+		Root:     Root{Path: "rootfs"},
+		Hostname: config.GetMetadata().GetName(),
+		Mounts: []Mount{
+			{Destination: "/proc", Type: "proc", Source: "proc"},
+			{Destination: "/dev", Type: "tmpfs", Source: "tmpfs", Options: []string{"nosuid", "strictatime", "mode=755", "size=65536k"}},
+			{Destination: "/dev/pts", Type: "devpts", Source: "devpts", Options: []string{"nosuid", "noexec", "newinstance", "ptmxmode=0666", "mode=0620"}},
+			{Destination: "/dev/shm", Type: "tmpfs", Source: "shm", Options: []string{"nosuid", "noexec", "nodev", "mode=1777", "size=65536k"}},
+			{Destination: "/dev/mqueue", Type: "mqueue", Source: "mqueue", Options: []string{"nosuid", "noexec", "nodev"}},
+			{Destination: "/sys", Type: "sysfs", Source: "sysfs", Options: []string{"nosuid", "noexec", "nodev", "ro"}},
+		},
+		Linux: Linux{
+			Namespaces: []Namespace{
+				{Type: "pid"}, {Type: "network"}, {Type: "ipc"}, {Type: "uts"}, {Type: "mount"},
+			},
+			Resources: NewResources(config.GetLinux().GetResources()),
+			MaskedPaths: []string{
+				"/proc/kcore", "/proc/latency_stats", "/proc/timer_list",
+				"/proc/timer_stats", "/proc/sched_debug", "/sys/firmware",
+			},
+			ReadonlyPaths: []string{
+				"/proc/asound", "/proc/bus", "/proc/fs", "/proc/irq", "/proc/sys", "/proc/sysrq-trigger",
+			},
+		},
+	}
+	for _, m := range config.Mounts {
+		options := []string{"rbind", "rw"}
+		if m.Readonly {
+			options[1] = "ro"
+		}
+		spec.Mounts = append(spec.Mounts, Mount{
+			Destination: m.ContainerPath,
+			Type:        "bind",
+			Source:      m.HostPath,
+			Options:     options,
+		})
+	}
+	return spec
+}
+
+// NewResources converts CRI resource limits. It returns nil if there are none.
+func NewResources(r *runtimeapi.LinuxContainerResources) *Resources {
+	if r == nil {
+		return nil
+	}
+	cpu := &CPU{Cpus: r.CpusetCpus, Mems: r.CpusetMems}
+	if r.CpuShares > 0 {
+		shares := uint64(r.CpuShares)
+		cpu.Shares = &shares
+	}
+	if r.CpuQuota > 0 {
+		quota := r.CpuQuota
+		cpu.Quota = &quota
+	}
+	if r.CpuPeriod > 0 {
+		period := uint64(r.CpuPeriod)
+		cpu.Period = &period
+	}
+	resources := &Resources{CPU: cpu}
+	if r.MemoryLimitInBytes > 0 {
+		limit := r.MemoryLimitInBytes
+		resources.Memory = &Memory{Limit: &limit}
+	}
+	return resources
+}
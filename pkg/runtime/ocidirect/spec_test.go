@@ -0,0 +1,65 @@
+package ocidirect
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestNewSpec(t *testing.T) {
+	spec := NewSpec(&runtimeapi.ContainerConfig{
+		Metadata:   &runtimeapi.ContainerMetadata{Name: "sysbench"},
+		Command:    []string{"sysbench"},
+		Args:       []string{"--test=cpu", "run"},
+		Envs:       []*runtimeapi.KeyValue{{Key: "FOO", Value: "bar"}},
+		WorkingDir: "/data",
+		Mounts: []*runtimeapi.Mount{
+			{ContainerPath: "/data", HostPath: "/var/tmp/data"},
+			{ContainerPath: "/config", HostPath: "/etc/config", Readonly: true},
+		},
+		Linux: &runtimeapi.LinuxContainerConfig{
+			Resources: &runtimeapi.LinuxContainerResources{CpuPeriod: 100000, CpuQuota: 10000},
+		},
+	})
+	if expected := []string{"sysbench", "--test=cpu", "run"}; !reflect.DeepEqual(spec.Process.Args, expected) {
+		t.Errorf("expected args %v, got %v", expected, spec.Process.Args)
+	}
+	if expected := []string{defaultPath, "FOO=bar"}; !reflect.DeepEqual(spec.Process.Env, expected) {
+		t.Errorf("expected env %v, got %v", expected, spec.Process.Env)
+	}
+	if spec.Process.Cwd != "/data" || spec.Hostname != "sysbench" || spec.Root.Path != "rootfs" {
+		t.Errorf("unexpected process settings cwd=%s hostname=%s root=%s", spec.Process.Cwd, spec.Hostname, spec.Root.Path)
+	}
+	binds := spec.Mounts[len(spec.Mounts)-2:]
+	expectedBinds := []Mount{
+		{Destination: "/data", Type: "bind", Source: "/var/tmp/data", Options: []string{"rbind", "rw"}},
+		{Destination: "/config", Type: "bind", Source: "/etc/config", Options: []string{"rbind", "ro"}},
+	}
+	if !reflect.DeepEqual(binds, expectedBinds) {
+		t.Errorf("expected bind mounts %v, got %v", expectedBinds, binds)
+	}
+	cpu := spec.Linux.Resources.CPU
+	if *cpu.Period != 100000 || *cpu.Quota != 10000 || cpu.Shares != nil {
+		t.Errorf("unexpected cpu resources %+v", cpu)
+	}
+	if spec.Linux.Resources.Memory != nil {
+		t.Errorf("expected no memory limit, got %+v", spec.Linux.Resources.Memory)
+	}
+}
+
+func TestImageRootfs(t *testing.T) {
+	os.Setenv(ImageDirEnv, "/images")
+	defer os.Unsetenv(ImageDirEnv)
+	tt := map[string]string{
+		"busybox":              "/images/busybox_latest",
+		"lnsp/sysbench:latest": "/images/lnsp_sysbench_latest",
+		"busybox:1.31":         "/images/busybox_1.31",
+	}
+	for image, expected := range tt {
+		if rootfs := imageRootfs(image); rootfs != expected {
+			t.Errorf("expected %s for %s, got %s", expected, image, rootfs)
+		}
+	}
+}
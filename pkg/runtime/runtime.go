@@ -20,6 +20,7 @@ import (
 
 const maxRemovalAttempts = 10
 const maxRemovalTimeout = 10
+const logPollInterval = 10 * time.Millisecond
 
 var uuidLock sync.Mutex
 var lastUUID uuid.UUID
@@ -43,11 +44,29 @@ const defaultNamespace = "touchstone"
 type Client struct {
 	Runtime runtimeapi.RuntimeServiceClient
 	Image   runtimeapi.ImageServiceClient
-	conn    *grpc.ClientConn
+	conn    io.Closer
+	api     string
 }
 
 var defaultLinuxPodLabels = map[string]string{}
 
+// VersionInfo fetches the name and version of the runtime and its CRI API.
+func (api *Client) VersionInfo() (*runtimeapi.VersionResponse, error) {
+	return api.Runtime.Version(context.Background(), &runtimeapi.VersionRequest{})
+}
+
+// RuntimeStatus fetches the runtime conditions. Verbose requests include runtime specific information like its configuration.
+func (api *Client) RuntimeStatus(verbose bool) (*runtimeapi.StatusResponse, error) {
+	return api.Runtime.Status(context.Background(), &runtimeapi.StatusRequest{
+		Verbose: verbose,
+	})
+}
+
+// APIVersion returns the negotiated CRI API version.
+func (api *Client) APIVersion() string {
+	return api.api
+}
+
 func (api *Client) Version() string {
 	resp, err := api.Runtime.Version(context.Background(), &runtimeapi.VersionRequest{})
 	if err != nil {
@@ -64,12 +83,15 @@ func (api *Client) Name() string {
 	return resp.RuntimeName
 }
 
-// CreateContainer runs a container image. It returns the container ID.
-func (api *Client) CreateContainer(sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string) (string, error) {
-	return api.CreateContainerWithResources(sandbox, pod, name, image, command, nil)
+// ContainerOptions holds optional container settings.
+type ContainerOptions struct {
+	Resources  *runtimeapi.LinuxContainerResources
+	Mounts     []*runtimeapi.Mount
+	WorkingDir string
 }
 
-func (api *Client) CreateContainerWithResources(sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, resources *runtimeapi.LinuxContainerResources) (string, error) {
+// CreateContainerWithOptions runs a container image with additional settings. It returns the container ID.
+func (api *Client) CreateContainerWithOptions(sandbox *runtimeapi.PodSandboxConfig, pod, name, image string, command []string, opts ContainerOptions) (string, error) {
 	container := &runtimeapi.ContainerConfig{
 		Metadata: &runtimeapi.ContainerMetadata{
 			Name:    name,
@@ -80,8 +102,10 @@ func (api *Client) CreateContainerWithResources(sandbox *runtimeapi.PodSandboxCo
 		},
 		LogPath: "/var/log/" + pod + "_" + name + ".log",
 		Linux: &runtimeapi.LinuxContainerConfig{
-			Resources: resources,
+			Resources: opts.Resources,
 		},
+		Mounts:     opts.Mounts,
+		WorkingDir: opts.WorkingDir,
 	}
 	if command != nil {
 		container.Command = command
@@ -98,27 +122,8 @@ func (api *Client) CreateContainerWithResources(sandbox *runtimeapi.PodSandboxCo
 	return resp.ContainerId, nil
 }
 
-// WaitForLogs waits for the container to exit and returns the logs as a slice of bytes.
-func (api *Client) WaitForLogs(container string) ([]byte, error) {
-	for {
-		status, err := api.Status(container)
-		if err != nil {
-			return nil, err
-		}
-		if status.State >= 2 {
-			break
-		}
-		<-time.After(time.Second)
-	}
-	buf := &bytes.Buffer{}
-	if err := api.Logs(container, buf); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// Logs fetches the logs of the container.
-func (api *Client) Logs(container string, writer io.Writer) error {
+// LogStreams fetches the logs of the container and splits them into stdout and stderr.
+func (api *Client) LogStreams(container string, stdout, stderr io.Writer) error {
 	status, err := api.Status(container)
 	if err != nil {
 		return err
@@ -142,8 +147,13 @@ func (api *Client) Logs(container string, writer io.Writer) error {
 			break
 		}
 		items := bytes.SplitN(line, []byte(" "), 4)
-		if len(items) == 4 {
-			fmt.Fprintln(writer, string(items[3]))
+		if len(items) != 4 {
+			continue
+		}
+		if string(items[1]) == "stderr" {
+			fmt.Fprintln(stderr, string(items[3]))
+		} else {
+			fmt.Fprintln(stdout, string(items[3]))
 		}
 	}
 	return nil
@@ -160,13 +170,43 @@ func (api *Client) Status(container string) (*runtimeapi.ContainerStatus, error)
 	return resp.Status, nil
 }
 
-// State fetches the state of the container.
-func (api *Client) State(container string) (runtimeapi.ContainerState, error) {
+// SandboxStatus fetches the status of a pod sandbox.
+func (api *Client) SandboxStatus(pod string) (*runtimeapi.PodSandboxStatus, error) {
+	resp, err := api.Runtime.PodSandboxStatus(context.Background(), &runtimeapi.PodSandboxStatusRequest{
+		PodSandboxId: pod,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+// FirstLogTime waits for the first line logged by the container and returns its timestamp.
+func (api *Client) FirstLogTime(container string, timeout time.Duration) (time.Time, error) {
 	status, err := api.Status(container)
 	if err != nil {
-		return 0, err
+		return time.Time{}, err
+	}
+	logPath := status.GetLogPath()
+	if logPath == "" {
+		return time.Time{}, errors.New("missing log path")
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.Open(logPath)
+		if err == nil {
+			line, _, err := bufio.NewReader(f).ReadLine()
+			f.Close()
+			if err == nil {
+				items := bytes.SplitN(line, []byte(" "), 2)
+				return time.Parse(time.RFC3339Nano, string(items[0]))
+			}
+		}
+		if time.Now().After(deadline) {
+			return time.Time{}, errors.Errorf("no logs from container %s after %v", container, timeout)
+		}
+		<-time.After(logPollInterval)
 	}
-	return status.State, nil
 }
 
 // StartContainer starts a new container instance.
@@ -227,20 +267,11 @@ func (api *Client) StartSandbox(sandbox *runtimeapi.PodSandboxConfig, runtime st
 	return resp.PodSandboxId, nil
 }
 
-// StopAndRemoveContainer stops and removes a container.
-func (api *Client) StopAndRemoveContainer(container string) (err error) {
-	for attempt := 0; attempt < maxRemovalAttempts; attempt++ {
-		err = api.StopContainer(container, maxRemovalTimeout)
-		if err != nil {
-			continue
-		}
-		err = api.RemoveContainer(container)
-		if err != nil {
-			continue
-		}
-		return nil
-	}
-	return errors.Errorf("stop-remove container failed: %v", err)
+// Sandbox is a running pod sandbox.
+type Sandbox struct {
+	ID      string
+	Handler string
+	Config  *runtimeapi.PodSandboxConfig
 }
 
 // StopSandbox stops the container instance.
@@ -265,36 +296,6 @@ func (api *Client) RemoveSandbox(pod string) error {
 	return nil
 }
 
-// StopAndRemoveSandbox stops and removes the given pod sandbox.
-func (api *Client) StopAndRemoveSandbox(pod string) (err error) {
-	for attempt := 0; attempt < maxRemovalAttempts; attempt++ {
-		err = api.StopSandbox(pod)
-		if err != nil {
-			continue
-		}
-		err = api.RemoveSandbox(pod)
-		if err != nil {
-			continue
-		}
-		return nil
-	}
-	return errors.Errorf("stop-remove pod failed: %v", err)
-}
-
-// InitLinuxSandbox creates a new pod sandbox configuration.
-func (api *Client) InitLinuxSandbox(name string) *runtimeapi.PodSandboxConfig {
-	return &runtimeapi.PodSandboxConfig{
-		Metadata: &runtimeapi.PodSandboxMetadata{
-			Name:      name,
-			Uid:       NewUUID(),
-			Namespace: defaultNamespace,
-			Attempt:   1,
-		},
-		Linux:  &runtimeapi.LinuxPodSandboxConfig{},
-		Labels: defaultLinuxPodLabels,
-	}
-}
-
 // PullImage instructs the CRI to pull an image from a public repository.
 func (api *Client) PullImage(image string, sandbox *runtimeapi.PodSandboxConfig) error {
 	if !strings.Contains(image, ":") {
@@ -312,24 +313,54 @@ func (api *Client) PullImage(image string, sandbox *runtimeapi.PodSandboxConfig)
 	return nil
 }
 
+// ExecSync runs a command in the container and waits for it to finish.
+func (api *Client) ExecSync(container string, cmd []string, timeout time.Duration) (*runtimeapi.ExecSyncResponse, error) {
+	return api.Runtime.ExecSync(context.Background(), &runtimeapi.ExecSyncRequest{
+		ContainerId: container,
+		Cmd:         cmd,
+		Timeout:     int64(timeout / time.Second),
+	})
+}
+
+// ContainerStats fetches the resource usage of a container.
+func (api *Client) ContainerStats(container string) (*runtimeapi.ContainerStats, error) {
+	resp, err := api.Runtime.ContainerStats(context.Background(), &runtimeapi.ContainerStatsRequest{
+		ContainerId: container,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Stats, nil
+}
+
 func (api *Client) Close() {
 	// TODO: close TCP connections
-	api.conn.Close()
+	if api.conn != nil {
+		api.conn.Close()
+	}
 }
 
 // NewClient instantiates a new API client.
+// It negotiates the CRI API version with the endpoint, preferring v1 over v1alpha2.
 func NewClient(addr string) (*Client, error) {
 	logrus.WithField("addr", addr).Debug("connecting to CRI endpoint")
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	version := &apiVersion{}
+	conn, err := grpc.Dial(addr, grpc.WithInsecure(), grpc.WithUnaryInterceptor(version.intercept))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to dial")
 	}
 	runtimeSvc := runtimeapi.NewRuntimeServiceClient(conn)
 	imageSvc := runtimeapi.NewImageServiceClient(conn)
+	if err := version.negotiate(runtimeSvc); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	logrus.WithFields(logrus.Fields{"addr": addr, "version": version.version}).Debug("negotiated CRI API version")
 	runtimeClient := &Client{
 		Runtime: runtimeSvc,
 		Image:   imageSvc,
 		conn:    conn,
+		api:     version.version,
 	}
 	return runtimeClient, nil
 }
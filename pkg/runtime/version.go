@@ -0,0 +1,59 @@
+package runtime
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// CRI API versions supported by the client, in order of preference.
+const (
+	APIVersionV1       = "v1"
+	APIVersionV1alpha2 = "v1alpha2"
+)
+
+const negotiationTimeout = 10 * time.Second
+
+// apiVersion selects the CRI API version requests are sent to.
+//
+// The runtime.v1 API started as a copy of runtime.v1alpha2 and both share the same
+// wire format, so the v1alpha2 client types are reused and only the gRPC service
+// name of each method is rewritten.
+type apiVersion struct {
+	version string
+}
+
+// intercept routes unary calls to the selected API version.
+func (v *apiVersion) intercept(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if v.version == APIVersionV1 {
+		method = strings.Replace(method, "/runtime."+APIVersionV1alpha2+".", "/runtime."+APIVersionV1+".", 1)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// negotiate probes the endpoint for the supported API versions, preferring v1.
+// Endpoints not implementing a version answer with codes.Unimplemented.
+func (v *apiVersion) negotiate(runtimeSvc runtimeapi.RuntimeServiceClient) error {
+	var err error
+	for _, version := range []string{APIVersionV1, APIVersionV1alpha2} {
+		v.version = version
+		ctx, cancel := context.WithTimeout(context.Background(), negotiationTimeout)
+		_, err = runtimeSvc.Version(ctx, &runtimeapi.VersionRequest{})
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if status.Code(err) != codes.Unimplemented {
+			return errors.Wrapf(err, "failed to probe CRI API %s", version)
+		}
+		logrus.WithField("version", version).Debug("CRI API version not supported by endpoint")
+	}
+	return errors.Wrap(err, "no supported CRI API version")
+}
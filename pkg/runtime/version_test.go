@@ -0,0 +1,79 @@
+package runtime
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// versionService serves the Version call of the runtime service in the given API version.
+func versionService(version string) *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: "runtime." + version + ".RuntimeService",
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{
+				MethodName: "Version",
+				Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+					if err := dec(&runtimeapi.VersionRequest{}); err != nil {
+						return nil, err
+					}
+					return &runtimeapi.VersionResponse{RuntimeName: "fake", RuntimeApiVersion: version}, nil
+				},
+			},
+		},
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	tt := []struct {
+		Name     string
+		Served   []string
+		Expected string
+	}{
+		{"v1", []string{APIVersionV1}, APIVersionV1},
+		{"v1alpha2", []string{APIVersionV1alpha2}, APIVersionV1alpha2},
+		{"both", []string{APIVersionV1alpha2, APIVersionV1}, APIVersionV1},
+		{"none", nil, ""},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("could not listen: %v", err)
+			}
+			server := grpc.NewServer()
+			for _, version := range tc.Served {
+				server.RegisterService(versionService(version), struct{}{})
+			}
+			go server.Serve(lis)
+			defer server.Stop()
+
+			client, err := NewClient(lis.Addr().String())
+			if tc.Expected == "" {
+				if err == nil {
+					client.Close()
+					t.Fatalf("expected negotiation to fail")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("could not connect: %v", err)
+			}
+			defer client.Close()
+			if client.APIVersion() != tc.Expected {
+				t.Errorf("expected API version %s, got %s", tc.Expected, client.APIVersion())
+			}
+			version, err := client.VersionInfo()
+			if err != nil {
+				t.Fatalf("could not fetch version: %v", err)
+			}
+			if version.RuntimeApiVersion != tc.Expected {
+				t.Errorf("expected call to be served by %s, got %s", tc.Expected, version.RuntimeApiVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Package stats implements the statistics used to compare benchmark results.
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// Tests comparing two samples.
+const (
+	// UTest is the Mann-Whitney U test, which does not assume normally distributed samples.
+	UTest = "utest"
+	// TTest is Welch's t-test, which assumes normally distributed samples of possibly different variance.
+	TTest = "ttest"
+)
+
+var (
+	// ErrSampleSize is returned if a sample has fewer than two values.
+	ErrSampleSize = errors.New("need at least two values per sample")
+	// ErrZeroMean is returned if the relative change to a baseline with zero mean is requested.
+	ErrZeroMean = errors.New("baseline mean is zero")
+	// ErrUnknownTest is returned for tests other than UTest and TTest.
+	ErrUnknownTest = errors.New("unknown test")
+)
+
+// maxExact is the largest sample size for which the exact U distribution is computed.
+const maxExact = 50
+
+// Mean returns the arithmetic mean of the values.
+func Mean(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// Variance returns the unbiased sample variance of the values.
+func Variance(xs []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	mean, sum := Mean(xs), 0.0
+	for _, x := range xs {
+		sum += (x - mean) * (x - mean)
+	}
+	return sum / float64(len(xs)-1)
+}
+
+// StdDev returns the sample standard deviation of the values.
+func StdDev(xs []float64) float64 {
+	return math.Sqrt(Variance(xs))
+}
+
+// Summary describes a sample.
+type Summary struct {
+	N      int     `json:"n"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+// Summarize describes the sample.
+func Summarize(xs []float64) Summary {
+	return Summary{N: len(xs), Mean: Mean(xs), StdDev: StdDev(xs)}
+}
+
+// Comparison is the change from a baseline to a candidate sample.
+type Comparison struct {
+	Baseline  Summary `json:"baseline"`
+	Candidate Summary `json:"candidate"`
+	// Delta is the change of the mean relative to the baseline mean.
+	Delta float64 `json:"delta"`
+	// Low and High bound the confidence interval of Delta.
+	Low  float64 `json:"low"`
+	High float64 `json:"high"`
+	// P is the two-sided p-value of the test.
+	P float64 `json:"p"`
+}
+
+// Compare tests the samples for a difference and estimates the relative change of the mean.
+// The confidence interval of the change, e.g. for a confidence of 0.95, treats the ratio of the means
+// as t-distributed with the standard error of the delta method.
+func Compare(baseline, candidate []float64, test string, confidence float64) (Comparison, error) {
+	if len(baseline) < 2 || len(candidate) < 2 {
+		return Comparison{}, ErrSampleSize
+	}
+	c := Comparison{Baseline: Summarize(baseline), Candidate: Summarize(candidate)}
+	if c.Baseline.Mean == 0 {
+		return Comparison{}, ErrZeroMean
+	}
+	_, _, tp := WelchTTest(baseline, candidate)
+	switch test {
+	case UTest:
+		_, c.P = MannWhitneyU(baseline, candidate)
+	case TTest:
+		c.P = tp
+	default:
+		return Comparison{}, ErrUnknownTest
+	}
+	c.Delta = (c.Candidate.Mean - c.Baseline.Mean) / math.Abs(c.Baseline.Mean)
+	c.Low, c.High = c.Delta, c.Delta
+	if se, df := ratioError(baseline, candidate); se > 0 {
+		margin := TQuantile(1-(1-confidence)/2, df) * se
+		c.Low, c.High = c.Delta-margin, c.Delta+margin
+	}
+	return c, nil
+}
+
+// ratioError returns the standard error of the ratio of the candidate to the baseline mean by the delta method,
+// and its degrees of freedom by the Welch-Satterthwaite equation.
+func ratioError(baseline, candidate []float64) (se, df float64) {
+	nx, ny := float64(len(baseline)), float64(len(candidate))
+	mx, my := Mean(baseline), Mean(candidate)
+	// variances of the ratio due to the candidate and the baseline mean
+	vy := Variance(candidate) / ny / (mx * mx)
+	vx := Variance(baseline) / nx * (my * my) / (mx * mx * mx * mx)
+	if vx+vy == 0 {
+		return 0, 0
+	}
+	return math.Sqrt(vx + vy), (vx + vy) * (vx + vy) / (vx*vx/(nx-1) + vy*vy/(ny-1))
+}
+
+// WelchTTest returns the t statistic, the degrees of freedom and the two-sided p-value of Welch's t-test.
+// Samples without variance yield a p-value of 1 if their means are equal and 0 otherwise.
+func WelchTTest(x, y []float64) (t, df, p float64) {
+	nx, ny := float64(len(x)), float64(len(y))
+	vx, vy := Variance(x)/nx, Variance(y)/ny
+	diff := Mean(x) - Mean(y)
+	if vx+vy == 0 {
+		if diff == 0 {
+			return 0, nx + ny - 2, 1
+		}
+		return math.Inf(int(math.Copysign(1, diff))), nx + ny - 2, 0
+	}
+	t = diff / math.Sqrt(vx+vy)
+	df = (vx + vy) * (vx + vy) / (vx*vx/(nx-1) + vy*vy/(ny-1))
+	return t, df, betaInc(df/(df+t*t), df/2, 0.5)
+}
+
+// TCDF returns the cumulative distribution function of Student's t distribution.
+func TCDF(t, df float64) float64 {
+	tail := 0.5 * betaInc(df/(df+t*t), df/2, 0.5)
+	if t < 0 {
+		return tail
+	}
+	return 1 - tail
+}
+
+// maxDoublings bounds the search for an upper bound of a quantile, beyond it the bound overflows.
+const maxDoublings = 1100
+
+// TQuantile returns the quantile function of Student's t distribution.
+// It returns NaN unless 0 < p < 1 and df > 0.
+func TQuantile(p, df float64) float64 {
+	if !(p > 0 && p < 1) || !(df > 0) {
+		return math.NaN()
+	}
+	if p < 0.5 {
+		return -TQuantile(1-p, df)
+	}
+	low, high := 0.0, 1.0
+	for i := 0; TCDF(high, df) < p; i++ {
+		if i == maxDoublings {
+			return math.Inf(1)
+		}
+		low, high = high, high*2
+	}
+	for i := 0; i < 100; i++ {
+		mid := (low + high) / 2
+		if TCDF(mid, df) < p {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return (low + high) / 2
+}
+
+// MannWhitneyU returns the U statistic of x, the number of pairs in which the value of x is larger,
+// and the two-sided p-value of the Mann-Whitney U test. The p-value is exact for small samples
+// without ties and uses the normal approximation with tie correction otherwise.
+func MannWhitneyU(x, y []float64) (u, p float64) {
+	type value struct {
+		v float64
+		x bool
+	}
+	values := make([]value, 0, len(x)+len(y))
+	for _, v := range x {
+		values = append(values, value{v, true})
+	}
+	for _, v := range y {
+		values = append(values, value{v, false})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].v < values[j].v })
+	var (
+		rankSum float64
+		ties    float64
+	)
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j].v == values[i].v {
+			j++
+		}
+		// tied values share the average of their ranks
+		rank := float64(i+j+1) / 2
+		for k := i; k < j; k++ {
+			if values[k].x {
+				rankSum += rank
+			}
+		}
+		t := float64(j - i)
+		ties += t*t*t - t
+		i = j
+	}
+	m, n := float64(len(x)), float64(len(y))
+	u = rankSum - m*(m+1)/2
+	if ties == 0 && len(x) <= maxExact && len(y) <= maxExact {
+		return u, exactU(len(x), len(y), int(u))
+	}
+	total := m + n
+	sigma := math.Sqrt(m * n / 12 * ((total + 1) - ties/(total*(total-1))))
+	if sigma == 0 {
+		return u, 1
+	}
+	z := (math.Abs(u-m*n/2) - 0.5) / sigma
+	if z < 0 {
+		z = 0
+	}
+	return u, math.Erfc(z / math.Sqrt2)
+}
+
+// exactU returns the two-sided p-value of U for samples of sizes m and n without ties.
+func exactU(m, n, u int) float64 {
+	counts := uCounts(m, n)
+	var total, below, above float64
+	for i, c := range counts {
+		total += c
+		if i <= u {
+			below += c
+		}
+		if i >= u {
+			above += c
+		}
+	}
+	return math.Min(1, 2*math.Min(below, above)/total)
+}
+
+// uCounts returns the number of orderings of samples of sizes m and n yielding each value of U.
+func uCounts(m, n int) []float64 {
+	// prev[j] holds the counts of sizes i-1 and j, cur[j] those of sizes i and j
+	prev := make([][]float64, n+1)
+	for j := range prev {
+		prev[j] = []float64{1}
+	}
+	for i := 1; i <= m; i++ {
+		cur := make([][]float64, n+1)
+		cur[0] = []float64{1}
+		for j := 1; j <= n; j++ {
+			counts := make([]float64, i*j+1)
+			// the largest value is from x and larger than all j values of y
+			for u, c := range prev[j] {
+				counts[u+j] += c
+			}
+			// the largest value is from y
+			for u, c := range cur[j-1] {
+				counts[u] += c
+			}
+			cur[j] = counts
+		}
+		prev = cur
+	}
+	return prev[n]
+}
+
+// betaInc returns the regularized incomplete beta function I_x(a, b).
+func betaInc(x, a, b float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaFraction(x, a, b) / a
+	}
+	return 1 - front*betaFraction(1-x, b, a)/b
+}
+
+// betaFraction evaluates the continued fraction of the incomplete beta function using Lentz's method.
+func betaFraction(x, a, b float64) float64 {
+	const (
+		maxIterations = 300
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+	clamp := func(v float64) float64 {
+		if math.Abs(v) < tiny {
+			return tiny
+		}
+		return v
+	}
+	c, d := 1.0, 1/clamp(1-(a+b)*x/(a+1))
+	h := d
+	for i := 1; i <= maxIterations; i++ {
+		m := float64(i)
+		even := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 / clamp(1+even*d)
+		c = clamp(1 + even/c)
+		h *= d * c
+		odd := -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 / clamp(1+odd*d)
+		c = clamp(1 + odd/c)
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,101 @@
+package stats
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+func TestWelchTTest(t *testing.T) {
+	stat, df, p := WelchTTest([]float64{1, 2, 3, 4, 5}, []float64{2, 4, 6, 8, 10})
+	if !near(stat, -1.897367, 1e-6) || !near(df, 5.882353, 1e-6) || !near(p, 0.107531, 1e-5) {
+		t.Errorf("expected t=-1.897367, df=5.882353, p=0.107531, got t=%f, df=%f, p=%f", stat, df, p)
+	}
+	if _, _, p := WelchTTest([]float64{1, 1}, []float64{1, 1}); p != 1 {
+		t.Errorf("expected p=1 for equal constant samples, got %f", p)
+	}
+	if _, _, p := WelchTTest([]float64{1, 1}, []float64{2, 2}); p != 0 {
+		t.Errorf("expected p=0 for different constant samples, got %f", p)
+	}
+}
+
+func TestTQuantile(t *testing.T) {
+	tt := []struct {
+		P, DF, Quantile float64
+	}{
+		{0.975, 1, 12.706205},
+		{0.975, 10, 2.228139},
+		{0.95, 30, 1.697261},
+		{0.025, 10, -2.228139},
+	}
+	for _, tc := range tt {
+		if q := TQuantile(tc.P, tc.DF); !near(q, tc.Quantile, 1e-5) {
+			t.Errorf("expected quantile %f of p=%f, df=%f, got %f", tc.Quantile, tc.P, tc.DF, q)
+		}
+	}
+	for _, tc := range []struct{ P, DF float64 }{{95, 10}, {0, 10}, {1, 10}, {-0.5, 10}, {0.975, 0}, {0.975, -1}, {math.NaN(), 10}} {
+		if q := TQuantile(tc.P, tc.DF); !math.IsNaN(q) {
+			t.Errorf("expected NaN for p=%f, df=%f, got %f", tc.P, tc.DF, q)
+		}
+	}
+	// the search for the upper bound ends for heavy tails
+	if q := TQuantile(1-1e-15, 0.01); !(q > 1e100) {
+		t.Errorf("expected huge quantile for heavy tails, got %g", q)
+	}
+}
+
+func TestMannWhitneyU(t *testing.T) {
+	tt := []struct {
+		Name string
+		X, Y []float64
+		U, P float64
+	}{
+		{"separated", []float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10}, 0, 2.0 / 252},
+		{"overlapping", []float64{1, 2, 3, 4, 8}, []float64{3.5, 5, 6, 7, 9, 10}, 5, 0.082251},
+		{"identical", []float64{1, 1, 1}, []float64{1, 1, 1}, 4.5, 1},
+	}
+	for _, tc := range tt {
+		t.Run(tc.Name, func(t *testing.T) {
+			u, p := MannWhitneyU(tc.X, tc.Y)
+			if u != tc.U || !near(p, tc.P, 1e-6) {
+				t.Errorf("expected U=%f, p=%f, got U=%f, p=%f", tc.U, tc.P, u, p)
+			}
+		})
+	}
+	// large samples with ties use the normal approximation
+	var x, y []float64
+	for i := 0; i < 60; i++ {
+		x, y = append(x, float64(i/2)), append(y, float64(i/2+10))
+	}
+	if _, p := MannWhitneyU(x, y); p > 0.01 {
+		t.Errorf("expected significant difference of shifted samples, got p=%f", p)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	baseline := []float64{10, 11, 9, 10, 10}
+	candidate := []float64{12, 13, 11, 12, 12}
+	c, err := Compare(baseline, candidate, UTest, 0.95)
+	if err != nil {
+		t.Fatalf("could not compare samples: %v", err)
+	}
+	// the delta method gives a standard error of 0.049396 with 7.748 degrees of freedom
+	if !near(c.Delta, 0.2, 1e-9) || !near(c.Low, 0.085444, 1e-5) || !near(c.High, 0.314556, 1e-5) {
+		t.Errorf("expected delta 0.2 within [0.085444, 0.314556], got %+v", c)
+	}
+	if c.P > 0.05 {
+		t.Errorf("expected significant change, got p=%f", c.P)
+	}
+	if _, err := Compare([]float64{1}, candidate, UTest, 0.95); err != ErrSampleSize {
+		t.Errorf("expected ErrSampleSize, got %v", err)
+	}
+	if _, err := Compare([]float64{0, 0}, candidate, TTest, 0.95); err != ErrZeroMean {
+		t.Errorf("expected ErrZeroMean, got %v", err)
+	}
+	if _, err := Compare(baseline, candidate, "ztest", 0.95); err != ErrUnknownTest {
+		t.Errorf("expected ErrUnknownTest, got %v", err)
+	}
+}
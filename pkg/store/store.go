@@ -0,0 +1,298 @@
+// Package store keeps the results of benchmark runs in a directory with one subdirectory per run ID.
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/environment"
+)
+
+// Files of a run directory.
+const (
+	ManifestFile = "manifest.json"
+	ConfigFile   = "config.yaml"
+	LogFile      = "touchstone.log"
+	resultsDir   = "results"
+)
+
+// Latest refers to the most recent completed run of a store.
+const Latest = "latest"
+
+// idFormat is the layout of run IDs, optionally followed by a numbered suffix.
+const idFormat = "20060102-150405"
+
+// Store is a directory of benchmark runs.
+type Store struct {
+	dir string
+}
+
+// New creates a store in the directory. The directory is created with the first run.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Create creates the directory of a new run with an ID derived from the time.
+// Runs started within the same second get a numbered suffix.
+func (s *Store) Create(now time.Time) (*Run, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return nil, err
+	}
+	base := now.UTC().Format(idFormat)
+	for i := 1; ; i++ {
+		id := base
+		if i > 1 {
+			id += "." + strconv.Itoa(i)
+		}
+		dir := filepath.Join(s.dir, id)
+		err := os.Mkdir(dir, 0755)
+		if os.IsExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return &Run{ID: id, Dir: dir}, nil
+	}
+}
+
+// parseID splits a run ID into its creation time and the suffix of runs started within the same second.
+func parseID(id string) (time.Time, int, bool) {
+	base, n := id, 1
+	if i := strings.IndexByte(id, '.'); i >= 0 {
+		suffix, err := strconv.Atoi(id[i+1:])
+		if err != nil || suffix < 2 {
+			return time.Time{}, 0, false
+		}
+		base, n = id[:i], suffix
+	}
+	created, err := time.Parse(idFormat, base)
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+	return created, n, true
+}
+
+// complete reports whether the run has a manifest and at least one result file.
+func (r *Run) complete() bool {
+	if _, err := os.Stat(r.Path(ManifestFile)); err != nil {
+		return false
+	}
+	names, err := r.ResultFiles()
+	return err == nil && len(names) > 0
+}
+
+// IDs returns the IDs of all completed runs, oldest first.
+// Directories that are not named like a run ID or lack a manifest or results are skipped.
+func (s *Store) IDs() ([]string, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		id      string
+		created time.Time
+		n       int
+	}
+	var entries []entry
+	for _, info := range infos {
+		if !info.IsDir() {
+			continue
+		}
+		created, n, ok := parseID(info.Name())
+		if !ok {
+			continue
+		}
+		run := &Run{ID: info.Name(), Dir: filepath.Join(s.dir, info.Name())}
+		if !run.complete() {
+			continue
+		}
+		entries = append(entries, entry{id: info.Name(), created: created, n: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].created.Equal(entries[j].created) {
+			return entries[i].created.Before(entries[j].created)
+		}
+		return entries[i].n < entries[j].n
+	})
+	ids := make([]string, len(entries))
+	for i, e := range entries {
+		ids[i] = e.id
+	}
+	return ids, nil
+}
+
+// Open opens an existing run. The ID may be Latest.
+func (s *Store) Open(id string) (*Run, error) {
+	if id == Latest {
+		ids, err := s.IDs()
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, fmt.Errorf("no completed runs in %s", s.dir)
+		}
+		id = ids[len(ids)-1]
+	}
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return nil, fmt.Errorf("invalid run ID %q", id)
+	}
+	dir := filepath.Join(s.dir, id)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("run %s not found in %s", id, s.dir)
+	}
+	return &Run{ID: id, Dir: dir}, nil
+}
+
+// Run is the directory of a benchmark run.
+type Run struct {
+	ID  string
+	Dir string
+}
+
+// Path returns the path of a file in the run directory.
+func (r *Run) Path(name string) string {
+	return filepath.Join(r.Dir, name)
+}
+
+// writeFile replaces the file atomically, so readers never see partial content.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteManifest stores the environment of the run.
+func (r *Run) WriteManifest(manifest *environment.Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(r.Path(ManifestFile), data)
+}
+
+// Manifest reads the environment of the run.
+func (r *Run) Manifest() (*environment.Manifest, error) {
+	data, err := ioutil.ReadFile(r.Path(ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := &environment.Manifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("%s: %v", r.Path(ManifestFile), err)
+	}
+	return manifest, nil
+}
+
+// WriteConfig stores the effective configs of the run.
+func (r *Run) WriteConfig(data []byte) error {
+	return writeFile(r.Path(ConfigFile), data)
+}
+
+// resultPath returns the path of a result file, which must stay inside the results directory.
+func (r *Run) resultPath(name string) (string, error) {
+	clean := filepath.Clean(name)
+	if name == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid result file name %q", name)
+	}
+	return filepath.Join(r.Dir, resultsDir, clean), nil
+}
+
+// WriteResults replaces the result file of the run with the given name.
+func (r *Run) WriteResults(name string, results *benchmark.Results) error {
+	path, err := r.resultPath(name)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return writeFile(path, append(data, '\n'))
+}
+
+// ResultFiles returns the names of the result files of the run, sorted by name.
+func (r *Run) ResultFiles() ([]string, error) {
+	root := filepath.Join(r.Dir, resultsDir)
+	var names []string
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return names, err
+}
+
+// ReadResults reads a result file of the run.
+func (r *Run) ReadResults(name string) ([]benchmark.Results, error) {
+	path, err := r.resultPath(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	results, err := benchmark.DecodeResults(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return results, nil
+}
+
+// Results reads all result files of the run.
+func (r *Run) Results() ([]benchmark.Results, error) {
+	names, err := r.ResultFiles()
+	if err != nil {
+		return nil, err
+	}
+	var all []benchmark.Results
+	for _, name := range names {
+		results, err := r.ReadResults(name)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, results...)
+	}
+	return all, nil
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/environment"
+)
+
+func TestStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("could not create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := New(dir)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	first, err := s.Create(now)
+	if err != nil {
+		t.Fatalf("could not create run: %v", err)
+	}
+	second, err := s.Create(now)
+	if err != nil {
+		t.Fatalf("could not create run: %v", err)
+	}
+	if first.ID != "20200102-030405" || second.ID != "20200102-030405.2" {
+		t.Errorf("unexpected run IDs %s and %s", first.ID, second.ID)
+	}
+	manifest := &environment.Manifest{Touchstone: "dev", CreatedAt: now}
+	if err := second.WriteManifest(manifest); err != nil {
+		t.Fatalf("could not write manifest: %v", err)
+	}
+	results := &benchmark.Results{
+		Environment: manifest,
+		Config:      "runs: 1\n",
+		Entries:     []benchmark.MatrixEntry{{CRI: "containerd", OCI: "runc"}},
+	}
+	// rewriting a result file replaces it
+	for i := 0; i < 2; i++ {
+		if err := second.WriteResults("performance.json", results); err != nil {
+			t.Fatalf("could not write results: %v", err)
+		}
+	}
+	if err := second.WriteResults("../escape.json", results); err == nil {
+		t.Errorf("expected error for result file outside the run")
+	}
+	latest, err := s.Open(Latest)
+	if err != nil {
+		t.Fatalf("could not open latest run: %v", err)
+	}
+	if latest.ID != second.ID {
+		t.Errorf("expected latest run %s, got %s", second.ID, latest.ID)
+	}
+	loaded, err := latest.Manifest()
+	if err != nil {
+		t.Fatalf("could not read manifest: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, manifest) {
+		t.Errorf("expected manifest %+v, got %+v", manifest, loaded)
+	}
+	docs, err := latest.Results()
+	if err != nil {
+		t.Fatalf("could not read results: %v", err)
+	}
+	if len(docs) != 1 || docs[0].Config != results.Config || !reflect.DeepEqual(docs[0].Entries, results.Entries) {
+		t.Errorf("expected results %+v, got %+v", results, docs)
+	}
+	if _, err := s.Open("missing"); err == nil {
+		t.Errorf("expected error for missing run")
+	}
+	if _, err := s.Open(".."); err == nil {
+		t.Errorf("expected error for invalid run ID")
+	}
+}
+
+func TestStoreIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "store_test")
+	if err != nil {
+		t.Fatalf("could not create tmpdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s := New(dir)
+	now := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	manifest := &environment.Manifest{Touchstone: "dev", CreatedAt: now}
+	results := &benchmark.Results{Config: "runs: 1\n"}
+	complete := func(run *Run) {
+		if err := run.WriteManifest(manifest); err != nil {
+			t.Fatalf("could not write manifest: %v", err)
+		}
+		if err := run.WriteResults("results.json", results); err != nil {
+			t.Fatalf("could not write results: %v", err)
+		}
+	}
+	var expected []string
+	for i := 1; i <= 10; i++ {
+		run, err := s.Create(now)
+		if err != nil {
+			t.Fatalf("could not create run: %v", err)
+		}
+		complete(run)
+		expected = append(expected, run.ID)
+	}
+	// a later run without results, e.g. one that failed, is skipped
+	failed, err := s.Create(now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("could not create run: %v", err)
+	}
+	if err := failed.WriteManifest(manifest); err != nil {
+		t.Fatalf("could not write manifest: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "other"), 0755); err != nil {
+		t.Fatalf("could not create directory: %v", err)
+	}
+	ids, err := s.IDs()
+	if err != nil {
+		t.Fatalf("could not list runs: %v", err)
+	}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected run IDs %v, got %v", expected, ids)
+	}
+	latest, err := s.Open(Latest)
+	if err != nil {
+		t.Fatalf("could not open latest run: %v", err)
+	}
+	if latest.ID != "20200102-030405.10" {
+		t.Errorf("expected latest run 20200102-030405.10, got %s", latest.ID)
+	}
+	// failed runs can still be opened by ID
+	if _, err := s.Open(failed.ID); err != nil {
+		t.Errorf("could not open failed run: %v", err)
+	}
+}
@@ -2,50 +2,24 @@ package util
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"strings"
-
-	"github.com/sirupsen/logrus"
 )
 
-func ParseSeconds(s string) float64 {
-	var f float64
-	fmt.Sscanf(s, "%f", &f)
-	return f
-}
-
-func ParsePrefixedLine(data []byte, prefix string) float64 {
-	return ParseSeconds(FindPrefixedLine(data, prefix))
-}
+// KnownCRIs are the CRI endpoints touchstone supports out of the box.
+var KnownCRIs = []string{"containerd", "crio"}
 
 func GetCRIEndpoint(runtime string) string {
 	return fmt.Sprintf("unix:///var/run/%s/%s.sock", runtime, runtime)
 }
 
-func GetOutputTarget(file string) io.WriteCloser {
-	var (
-		out io.WriteCloser = os.Stdout
-		err error
-	)
-	if file != "-" {
-		out, err = os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			out = os.Stdout
-		}
-	}
-	return out
-}
-
-func FindPrefixedLine(data []byte, prefix string) string {
-	lines := strings.Split(string(data), "\n")
-	for _, l := range lines {
-		trimmed := strings.TrimSpace(l)
-		if strings.HasPrefix(trimmed, prefix) {
-			return strings.TrimSpace(strings.TrimPrefix(trimmed, prefix))
+// HasCRIEndpoint checks if the CRI is known or its endpoint socket exists on this host.
+func HasCRIEndpoint(runtime string) bool {
+	for _, known := range KnownCRIs {
+		if known == runtime {
+			return true
 		}
 	}
-	fmt.Println(string(data))
-	logrus.Fatalf("failed to find %s", prefix)
-	return ""
+	_, err := os.Stat(strings.TrimPrefix(GetCRIEndpoint(runtime), "unix://"))
+	return err == nil
 }
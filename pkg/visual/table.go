@@ -0,0 +1,152 @@
+package visual
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+)
+
+// labels returns the labels of the result in index order, followed by unindexed ones.
+func labels(index benchmark.Index, result benchmark.MatrixResult) []string {
+	labels := append([]string{}, index[result.Name].Labels...)
+	values, _ := result.Aggregated.(benchmark.ValueReport)
+	var extra []string
+	for label := range values {
+		if !contains(labels, label) {
+			extra = append(extra, label)
+		}
+	}
+	sort.Strings(extra)
+	return append(labels, extra...)
+}
+
+func contains(slice []string, s string) bool {
+	for _, item := range slice {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the aggregated value of the label.
+func value(result benchmark.MatrixResult, label string) (float64, bool) {
+	values, ok := result.Aggregated.(benchmark.ValueReport)
+	if !ok {
+		return 0, false
+	}
+	v, ok := values[label]
+	return v, ok
+}
+
+// source returns the source of the ith entry, empty if the report has a single one.
+func (r *Report) source(i int) string {
+	if i < len(r.Sources) {
+		return r.Sources[i]
+	}
+	return ""
+}
+
+// column returns the title of the ith entry, its CRI and handler followed by its source.
+func (r *Report) column(i int) string {
+	entry := r.Entries[i]
+	name := entry.CRI + "/" + entry.OCI
+	if source := r.source(i); source != "" {
+		name += " (" + source + ")"
+	}
+	return name
+}
+
+// CSV writes a row with the aggregated value of each label of the results.
+// Rows start with the source of their entry if the report has several.
+func CSV(w io.Writer, report *Report) error {
+	writer := csv.NewWriter(w)
+	var header []string
+	if len(report.Sources) > 0 {
+		header = append(header, "source")
+	}
+	writer.Write(append(header, "cri", "oci", "benchmark", "label", "value", "reports", "failures"))
+	for i, entry := range report.Entries {
+		for _, result := range entry.Results {
+			for _, label := range labels(report.Index, result) {
+				v, ok := value(result, label)
+				if !ok {
+					continue
+				}
+				var row []string
+				if len(report.Sources) > 0 {
+					row = append(row, report.source(i))
+				}
+				writer.Write(append(row,
+					entry.CRI,
+					entry.OCI,
+					result.Name,
+					label,
+					strconv.FormatFloat(v, 'g', -1, 64),
+					strconv.Itoa(len(result.Reports)),
+					strconv.Itoa(len(result.Failures)),
+				))
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Markdown writes a table of each benchmark with a column of aggregated values per CRI and handler.
+// Columns are titled with the source of their entry if the report has several.
+func Markdown(w io.Writer, report *Report) error {
+	type column struct {
+		name   string
+		result benchmark.MatrixResult
+	}
+	var (
+		names   []string
+		columns = make(map[string][]column)
+	)
+	for i, entry := range report.Entries {
+		for _, result := range entry.Results {
+			if _, ok := columns[result.Name]; !ok {
+				names = append(names, result.Name)
+			}
+			columns[result.Name] = append(columns[result.Name], column{report.column(i), result})
+		}
+	}
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "### %s\n\n", name)
+		if description := report.Index[name].Description; description != "" {
+			fmt.Fprintf(w, "%s\n\n", description)
+		}
+		header, separator := []string{"label"}, []string{"---"}
+		var rows []string
+		for _, c := range columns[name] {
+			header, separator = append(header, c.name), append(separator, "---:")
+			for _, label := range labels(report.Index, c.result) {
+				if !contains(rows, label) {
+					rows = append(rows, label)
+				}
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(separator, " | "))
+		for _, label := range rows {
+			row := []string{label}
+			for _, c := range columns[name] {
+				cell := "-"
+				if v, ok := value(c.result, label); ok {
+					cell = strconv.FormatFloat(v, 'g', 4, 64)
+				}
+				row = append(row, cell)
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+	return nil
+}
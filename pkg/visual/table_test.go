@@ -0,0 +1,131 @@
+package visual
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/lnsp/touchstone/pkg/benchmark"
+)
+
+func testReport() *Report {
+	return &Report{
+		Entries: []benchmark.MatrixEntry{
+			{CRI: "containerd", OCI: "runc", Results: []benchmark.MatrixResult{{
+				Name:       "operations.start",
+				Aggregated: benchmark.ValueReport{"Start": 0.25, "Stop": 1.5},
+				Reports:    []benchmark.Report{benchmark.ValueReport{}, benchmark.ValueReport{}},
+			}}},
+			{CRI: "crio", OCI: "runc", Results: []benchmark.MatrixResult{{
+				Name:       "operations.start",
+				Aggregated: benchmark.ValueReport{"Start": 0.5},
+				Failures:   []benchmark.Failure{{Run: 0, Error: "failed"}},
+			}}},
+		},
+		Index: benchmark.Index{
+			"operations.start": {Description: "Starts a container.", Labels: []string{"Stop", "Start"}},
+		},
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := CSV(&buf, testReport()); err != nil {
+		t.Fatalf("could not write csv: %v", err)
+	}
+	expected := `cri,oci,benchmark,label,value,reports,failures
+containerd,runc,operations.start,Stop,1.5,2,0
+containerd,runc,operations.start,Start,0.25,2,0
+crio,runc,operations.start,Start,0.5,0,1
+`
+	if buf.String() != expected {
+		t.Errorf("expected csv\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Markdown(&buf, testReport()); err != nil {
+		t.Fatalf("could not write markdown: %v", err)
+	}
+	expected := `### operations.start
+
+Starts a container.
+
+| label | containerd/runc | crio/runc |
+| --- | ---: | ---: |
+| Stop | 1.5 | - |
+| Start | 0.25 | 0.5 |
+`
+	if buf.String() != expected {
+		t.Errorf("expected markdown\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func sourcesReport() *Report {
+	return &Report{
+		Entries: []benchmark.MatrixEntry{
+			{CRI: "containerd", OCI: "runc", Results: []benchmark.MatrixResult{{
+				Name:       "operations.start",
+				Aggregated: benchmark.ValueReport{"Start": 0.25},
+			}}},
+			{CRI: "containerd", OCI: "runc", Results: []benchmark.MatrixResult{{
+				Name:       "operations.start",
+				Aggregated: benchmark.ValueReport{"Start": 0.5, "Stop": 1.5},
+			}}},
+		},
+		Sources: []string{"alpha", "beta"},
+		Index:   benchmark.Index{"operations.start": {Labels: []string{"Start"}}},
+	}
+}
+
+func TestSources(t *testing.T) {
+	var buf bytes.Buffer
+	if err := CSV(&buf, sourcesReport()); err != nil {
+		t.Fatalf("could not write csv: %v", err)
+	}
+	expected := `source,cri,oci,benchmark,label,value,reports,failures
+alpha,containerd,runc,operations.start,Start,0.25,0,0
+beta,containerd,runc,operations.start,Start,0.5,0,0
+beta,containerd,runc,operations.start,Stop,1.5,0,0
+`
+	if buf.String() != expected {
+		t.Errorf("expected csv\n%s\ngot\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+	if err := HTML(&buf, sourcesReport()); err != nil {
+		t.Fatalf("could not write html: %v", err)
+	}
+	if !strings.Contains(buf.String(), `let columns = ["containerd/runc (alpha)","containerd/runc (beta)"];`) {
+		t.Errorf("expected html datasets labeled by source")
+	}
+
+	buf.Reset()
+	if err := Markdown(&buf, sourcesReport()); err != nil {
+		t.Fatalf("could not write markdown: %v", err)
+	}
+	expected = `### operations.start
+
+| label | containerd/runc (alpha) | containerd/runc (beta) |
+| --- | ---: | ---: |
+| Start | 0.25 | 0.5 |
+| Stop | - | 1.5 |
+`
+	if buf.String() != expected {
+		t.Errorf("expected markdown\n%s\ngot\n%s", expected, buf.String())
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for _, name := range []string{"index.html", "report.csv", "dir/report.md"} {
+		if _, err := FormatOf(name); err != nil {
+			t.Errorf("expected format of %s, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"index.htm", "report", "report.md.txt"} {
+		if _, err := FormatOf(name); err == nil {
+			t.Errorf("expected error for %s", name)
+		}
+	}
+}
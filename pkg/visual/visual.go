@@ -2,14 +2,20 @@ package visual
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/template"
 
 	"github.com/lnsp/touchstone/pkg/benchmark"
+	"github.com/lnsp/touchstone/pkg/environment"
 )
 
-var tmpl = template.Must(template.New("").Parse(`
+var tmpl = template.Must(template.New("").Funcs(template.FuncMap{
+	"mib": func(bytes uint64) uint64 { return bytes / (1 << 20) },
+}).Parse(`
 <!doctype html>
 <html>
 
@@ -28,6 +34,29 @@ var tmpl = template.Must(template.New("").Parse(`
         		<h1>Touchstone</h1>
             </div>
         </div>
+        {{ range .Environments }}
+        <div class="row text-muted small">
+            <div class="col">
+                <dl class="row mb-0">
+                    <dt class="col-sm-3">touchstone</dt><dd class="col-sm-9">{{ html .Touchstone }}</dd>
+                    <dt class="col-sm-3">Collected</dt><dd class="col-sm-9">{{ .CreatedAt.Format "2006-01-02 15:04:05 MST" }}</dd>
+                    {{ with .Host }}
+                    <dt class="col-sm-3">Host</dt><dd class="col-sm-9">{{ html .Hostname }} ({{ html .OS }}, {{ html .Arch }})</dd>
+                    <dt class="col-sm-3">Kernel</dt><dd class="col-sm-9">{{ html .Kernel }}</dd>
+                    <dt class="col-sm-3">CPU</dt><dd class="col-sm-9">{{ html .CPUModel }}, {{ .CPUs }} cores</dd>
+                    <dt class="col-sm-3">Memory</dt><dd class="col-sm-9">{{ mib .MemoryTotal }} MiB</dd>
+                    <dt class="col-sm-3">Cgroups</dt><dd class="col-sm-9">v{{ .CgroupVersion }}</dd>
+                    {{ end }}
+                    {{ range .Runtimes }}
+                    <dt class="col-sm-3">{{ html .CRI }}</dt><dd class="col-sm-9">{{ if .Error }}unavailable: {{ html .Error }}{{ else }}{{ html .Name }} {{ html .Version }} (CRI {{ if .CRIVersion }}{{ html .CRIVersion }}{{ else }}{{ html .APIVersion }}{{ end }}){{ end }}</dd>
+                    {{ end }}
+                    {{ range .Handlers }}
+                    <dt class="col-sm-3">{{ html .Name }}</dt><dd class="col-sm-9">{{ if .Backend }}provided by {{ html .Backend }}{{ else if .Version }}{{ html .Version }}{{ else }}unknown{{ end }}</dd>
+                    {{ end }}
+                </dl>
+            </div>
+        </div>
+        {{ end }}
         <hr>
     </header>
     <main class="container">
@@ -51,15 +80,25 @@ var tmpl = template.Must(template.New("").Parse(`
             let dataContainer = document.getElementById("data");
             let datasets = {{ .Datasets }};
             let indices = {{ .Indices }};
+            let columns = {{ .Columns }};
 
 			let colors = {
     			'containerd/runc': 'rgba(30,136,229,0.5)',
     			'containerd/runsc': 'rgba(103,58,183,0.5)',
     			'crio/runc': 'rgba(216,27,96,0.5)',
     			'crio/runsc': 'rgba(244,67,54,0.5)',
+    			'native/host': 'rgba(97,97,97,0.5)',
+    			'native/unshare': 'rgba(158,158,158,0.5)',
+    			'oci-direct/runc': 'rgba(0,150,136,0.5)',
+    			'oci-direct/runsc': 'rgba(255,152,0,0.5)',
+    			'docker/runc': 'rgba(0,188,212,0.5)',
+    			'docker/runsc': 'rgba(63,81,181,0.5)',
+    			'podman/runc': 'rgba(139,195,74,0.5)',
+    			'podman/runsc': 'rgba(121,85,72,0.5)',
 			};
 
-            for (op of datasets) {
+            for (let i = 0; i < datasets.length; i++) {
+                let op = datasets[i];
                 console.log("Indexing over " + op.cri + "/" + op.oci);
                 for (result of op.results) {
 					// Generate index for median computation
@@ -80,7 +119,7 @@ var tmpl = template.Must(template.New("").Parse(`
                         aggregated.push(median(valueGroups[label]));
                     }
                     indices[result.name].datasets.push({
-                        label: op.cri + "/" + op.oci,
+                        label: columns[i],
                         data: aggregated,
                         borderWidth: 1,
                         backgroundColor: colors[op.cri+'/'+op.oci],
@@ -131,31 +170,72 @@ var tmpl = template.Must(template.New("").Parse(`
 </html>
 `))
 
-func HTML(w io.Writer, reportsJSON, indicesJSON []byte) error {
-	if err := tmpl.Execute(w, struct {
-		Datasets, Indices string
-	}{string(reportsJSON), string(indicesJSON)}); err != nil {
-		return err
-	}
-	return nil
+// Report holds the results rendered by the report formats.
+type Report struct {
+	// Environments are the manifests of the hosts that produced the results.
+	Environments []*environment.Manifest
+	Entries      []benchmark.MatrixEntry
+	// Sources name the host or run of each entry if the entries come from several, empty otherwise.
+	Sources []string
+	Index   benchmark.Index
 }
 
-func Write(name string, entries []benchmark.MatrixEntry, index benchmark.Index) error {
-	f, err := os.Create(name)
+// Format writes a report.
+type Format func(w io.Writer, report *Report) error
+
+// Formats are the supported report formats by file extension.
+var Formats = map[string]Format{
+	"html": HTML,
+	"csv":  CSV,
+	"md":   Markdown,
+}
+
+// HTML writes the report as page with a chart of each benchmark.
+func HTML(w io.Writer, report *Report) error {
+	entriesBytes, err := json.Marshal(report.Entries)
+	if err != nil {
+		return err
+	}
+	indexBytes, err := json.Marshal(report.Index)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	entriesBytes, err := json.Marshal(entries)
+	columns := make([]string, len(report.Entries))
+	for i := range report.Entries {
+		columns[i] = report.column(i)
+	}
+	columnsBytes, err := json.Marshal(columns)
 	if err != nil {
 		return err
 	}
-	indexBytes, err := json.Marshal(index)
+	return tmpl.Execute(w, struct {
+		Environments               []*environment.Manifest
+		Datasets, Indices, Columns string
+	}{report.Environments, string(entriesBytes), string(indexBytes), string(columnsBytes)})
+}
+
+// FormatOf returns the format given by the extension of the file name.
+func FormatOf(name string) (Format, error) {
+	format, ok := Formats[strings.TrimPrefix(filepath.Ext(name), ".")]
+	if !ok {
+		return nil, fmt.Errorf("unsupported report format of %s", name)
+	}
+	return format, nil
+}
+
+// Write writes the report to the file in the format given by its extension.
+func Write(name string, report *Report) error {
+	format, err := FormatOf(name)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
 	if err != nil {
 		return err
 	}
-	if err := HTML(f, entriesBytes, indexBytes); err != nil {
+	if err := format(f, report); err != nil {
+		f.Close()
 		return err
 	}
-	return nil
+	return f.Close()
 }